@@ -0,0 +1,27 @@
+// Package bltea connects bubblelayout to Bubble Tea. It is a separate module so that the
+// bubblelayout package does not depend on Bubble Tea.
+package bltea
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	bl "github.com/winder/bubblelayout"
+)
+
+// ResizeErrorMsg is returned by ResizeCmd when the layout cannot be resized, see BubbleLayout.TryResize.
+type ResizeErrorMsg struct {
+	Err error
+}
+
+// ResizeCmd resizes the layout to the size of the window. The command returns the bl.BubbleLayoutMsg, or a
+// ResizeErrorMsg if the layout is not valid. The layout is resized when ResizeCmd is called rather than
+// when the command runs, so it is safe to modify the layout in Update afterwards.
+func ResizeCmd(layout bl.BubbleLayout, msg tea.WindowSizeMsg) tea.Cmd {
+	resized, err := layout.TryResize(msg.Width, msg.Height)
+	return func() tea.Msg {
+		if err != nil {
+			return ResizeErrorMsg{Err: err}
+		}
+		return resized
+	}
+}
@@ -0,0 +1,98 @@
+package bltea
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	bl "github.com/winder/bubblelayout"
+)
+
+// Model is a container for the models of a layout. When the window is resized the layout is resized, and
+// each child whose size changed is sent the size of its content area as a tea.WindowSizeMsg. Hidden children
+// are sent a zero size. Other messages are sent to every child, and the views of the children are composited
+// with bl.Render.
+//
+// Bubble Tea sends a tea.WindowSizeMsg when the program starts, so there is nothing to render until then.
+type Model struct {
+	layout   bl.BubbleLayout
+	children Router
+
+	// window is the most recent window size, it is used by Refresh.
+	window tea.WindowSizeMsg
+	msg    bl.BubbleLayoutMsg
+	err    error
+}
+
+// New creates a container for the layout, models are added with Add.
+func New(layout bl.BubbleLayout) Model {
+	return Model{
+		layout:   layout,
+		children: Router{WindowSize: true},
+	}
+}
+
+// Add sets the model for a view of the layout and returns the updated container. If the view already has a
+// model it is replaced.
+func (m Model) Add(id bl.ID, child tea.Model) Model {
+	m.children = m.children.Register(id, child)
+	return m
+}
+
+// Child returns the model for a view.
+func (m Model) Child(id bl.ID) (tea.Model, bool) {
+	return m.children.Model(id)
+}
+
+// Layout returns the most recent layout.
+func (m Model) Layout() bl.BubbleLayoutMsg {
+	return m.msg
+}
+
+// Err returns the error from the most recent resize, if the layout was not valid.
+func (m Model) Err() error {
+	return m.err
+}
+
+// Refresh resizes the layout to the most recent window size. It is needed after the layout is changed, for
+// example with SetVisible.
+func (m Model) Refresh() tea.Cmd {
+	return ResizeCmd(m.layout, m.window)
+}
+
+// Init initializes the children.
+func (m Model) Init() tea.Cmd {
+	ids := m.children.IDs()
+	cmds := make([]tea.Cmd, 0, len(ids))
+	for _, id := range ids {
+		child, _ := m.children.Model(id)
+		cmds = append(cmds, child.Init())
+	}
+	return tea.Batch(cmds...)
+}
+
+// Update resizes the layout when the window is resized, and sends the other messages to the children.
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.window = msg
+		return m, ResizeCmd(m.layout, msg)
+	case ResizeErrorMsg:
+		m.err = msg.Err
+		return m, nil
+	case bl.BubbleLayoutMsg:
+		m.msg, m.err = msg, nil
+		m.children, cmd = m.children.Route(msg)
+		return m, cmd
+	}
+
+	m.children, cmd = m.children.Broadcast(msg)
+	return m, cmd
+}
+
+// View composites the views of the children. If the layout is not valid the error is displayed instead.
+func (m Model) View() string {
+	if m.err != nil {
+		return m.err.Error()
+	}
+	return bl.Render(m.msg, m.children.Views())
+}
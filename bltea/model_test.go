@@ -0,0 +1,146 @@
+package bltea_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	bl "github.com/winder/bubblelayout"
+	"github.com/winder/bubblelayout/bltea"
+)
+
+// sizeModel records the messages it receives and renders its size.
+type sizeModel struct {
+	name string
+	size tea.WindowSizeMsg
+	msgs []tea.Msg
+}
+
+func (m sizeModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m sizeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m.msgs = append(m.msgs, msg)
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		m.size = size
+	}
+	return m, nil
+}
+
+func (m sizeModel) View() string {
+	return strings.Repeat(m.name, m.size.Width)
+}
+
+// run sends a message to the model, and then the messages from the commands it returns.
+func run(t *testing.T, m tea.Model, msg tea.Msg) tea.Model {
+	t.Helper()
+	m, cmd := m.Update(msg)
+	if cmd == nil {
+		return m
+	}
+	next := cmd()
+	if batch, ok := next.(tea.BatchMsg); ok {
+		for _, c := range batch {
+			if c != nil {
+				m = run(t, m, c())
+			}
+		}
+		return m
+	}
+	return run(t, m, next)
+}
+
+func TestResizeCmd(t *testing.T) {
+	l := bl.New()
+	id := l.Add("")
+
+	msg := bltea.ResizeCmd(l, tea.WindowSizeMsg{Width: 10, Height: 2})()
+	require.IsType(t, bl.BubbleLayoutMsg{}, msg)
+	size, err := msg.(bl.BubbleLayoutMsg).Size(id)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Size{Width: 10, Height: 2}, size)
+
+	l = bl.New()
+	l.Cell(bl.Cell{SpanWidth: -1})
+	msg = bltea.ResizeCmd(l, tea.WindowSizeMsg{Width: 10, Height: 2})()
+	require.IsType(t, bltea.ResizeErrorMsg{}, msg)
+	assert.True(t, errors.As(msg.(bltea.ResizeErrorMsg).Err, &bl.ErrSpan{}))
+}
+
+func TestModel(t *testing.T) {
+	l := bl.New()
+	left := l.Add("width 3!")
+	right := l.Add("border")
+	hidden := l.Add("dock south 1!, hidden, hidemode collapse")
+
+	var m tea.Model = bltea.New(l).
+		Add(left, sizeModel{name: "a"}).
+		Add(right, sizeModel{name: "b"}).
+		Add(hidden, sizeModel{name: "c"})
+	assert.Nil(t, m.Init())
+	assert.Equal(t, "", m.View())
+
+	m = run(t, m, tea.WindowSizeMsg{Width: 8, Height: 4})
+	container := m.(bltea.Model)
+	require.NoError(t, container.Err())
+
+	// each child is sent the size of its content area.
+	expected := map[bl.ID]tea.WindowSizeMsg{
+		left:   {Width: 3, Height: 4},
+		right:  {Width: 3, Height: 2},
+		hidden: {},
+	}
+	for id, size := range expected {
+		child, ok := container.Child(id)
+		require.True(t, ok)
+		assert.Equal(t, []tea.Msg{size}, child.(sizeModel).msgs, "id %d", id)
+	}
+	assert.Equal(t, strings.Join([]string{
+		"aaa┌───┐",
+		"   │bbb│",
+		"   │   │",
+		"   └───┘",
+	}, "\n"), m.View())
+
+	// other messages are sent to every child.
+	key := tea.KeyMsg{Type: tea.KeyEnter}
+	m = run(t, m, key)
+	for id := range expected {
+		child, _ := m.(bltea.Model).Child(id)
+		msgs := child.(sizeModel).msgs
+		assert.Equal(t, key, msgs[len(msgs)-1], "id %d", id)
+	}
+}
+
+func TestModel_Refresh(t *testing.T) {
+	l := bl.New()
+	left := l.Add("")
+	right := l.Add("hidemode collapse")
+
+	m := bltea.New(l).Add(left, sizeModel{name: "a"})
+	next := run(t, m, tea.WindowSizeMsg{Width: 10, Height: 1})
+	m = next.(bltea.Model)
+	child, _ := m.Child(left)
+	assert.Equal(t, 5, child.(sizeModel).size.Width)
+
+	l.SetVisible(right, false)
+	next = run(t, m, m.Refresh()())
+	m = next.(bltea.Model)
+	child, _ = m.Child(left)
+	assert.Equal(t, 10, child.(sizeModel).size.Width)
+}
+
+func TestModel_Error(t *testing.T) {
+	l := bl.New()
+	l.Cell(bl.Cell{SpanWidth: -1})
+
+	next := run(t, bltea.New(l), tea.WindowSizeMsg{Width: 10, Height: 1})
+	m := next.(bltea.Model)
+	require.Error(t, m.Err())
+	assert.Equal(t, m.Err().Error(), m.View())
+}
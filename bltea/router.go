@@ -0,0 +1,118 @@
+package bltea
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	bl "github.com/winder/bubblelayout"
+)
+
+// Router delivers the size of each view only to the model registered for its ID, instead of sending the
+// whole bl.BubbleLayoutMsg to every model. A model is only sent its size when bl.BubbleLayoutMsg.Diff reports
+// that its view changed, so models are not updated when the layout changes elsewhere. The zero value is ready
+// to use.
+//
+// A Router is a value, its methods return an updated copy and never modify the receiver.
+type Router struct {
+	// WindowSize sends each model a tea.WindowSizeMsg with the size of its view instead of a
+	// bl.ComponentSizeMsg. This is for models which do not know about bubblelayout.
+	WindowSize bool
+
+	// ids are the registered views, in the order they were registered.
+	ids    []bl.ID
+	models map[bl.ID]tea.Model
+	// last is the most recent layout passed to Route.
+	last bl.BubbleLayoutMsg
+	// pending are the views whose model was registered after the last Route, they are sent their size even
+	// if the view did not change.
+	pending map[bl.ID]bool
+}
+
+// Register sets the model for a view and returns the updated router. If the view already has a model it
+// is replaced, and the new model is sent its size by the next Route.
+func (r Router) Register(id bl.ID, model tea.Model) Router {
+	if _, ok := r.models[id]; !ok {
+		r.ids = append(append([]bl.ID{}, r.ids...), id)
+	}
+	r.models = r.cloneModels()
+	r.models[id] = model
+
+	pending := make(map[bl.ID]bool, len(r.pending)+1)
+	for k := range r.pending {
+		pending[k] = true
+	}
+	pending[id] = true
+	r.pending = pending
+	return r
+}
+
+// Model returns the model registered for a view.
+func (r Router) Model(id bl.ID) (tea.Model, bool) {
+	model, ok := r.models[id]
+	return model, ok
+}
+
+// IDs returns the registered views, in the order they were registered.
+func (r Router) IDs() []bl.ID {
+	return append([]bl.ID{}, r.ids...)
+}
+
+// Route splits the layout with bl.BubbleLayoutMsg.Components and sends each message to the model registered
+// for its view, if the view changed since the previous Route or the model was registered after it. Views
+// without a model are ignored.
+func (r Router) Route(msg bl.BubbleLayoutMsg) (Router, tea.Cmd) {
+	changed := make(map[bl.ID]bool)
+	for _, id := range r.last.Diff(msg) {
+		changed[id] = true
+	}
+
+	components := msg.Components()
+	cmds := make([]tea.Cmd, 0, len(components))
+	r.models = r.cloneModels()
+	for _, c := range components {
+		if _, ok := r.models[c.ID]; !ok || !(changed[c.ID] || r.pending[c.ID]) {
+			continue
+		}
+		var sizeMsg tea.Msg = c
+		if r.WindowSize {
+			sizeMsg = tea.WindowSizeMsg{Width: c.Rect.Width, Height: c.Rect.Height}
+		}
+		cmds = append(cmds, r.update(c.ID, sizeMsg))
+	}
+	r.last, r.pending = msg, nil
+	return r, tea.Batch(cmds...)
+}
+
+// Broadcast sends a message to every registered model.
+func (r Router) Broadcast(msg tea.Msg) (Router, tea.Cmd) {
+	cmds := make([]tea.Cmd, 0, len(r.ids))
+	r.models = r.cloneModels()
+	for _, id := range r.ids {
+		cmds = append(cmds, r.update(id, msg))
+	}
+	return r, tea.Batch(cmds...)
+}
+
+// Views returns the view of every registered model, it can be passed directly to bl.Render.
+func (r Router) Views() map[bl.ID]string {
+	views := make(map[bl.ID]string, len(r.ids))
+	for _, id := range r.ids {
+		views[id] = r.models[id].View()
+	}
+	return views
+}
+
+// cloneModels copies the models so that they can be modified without modifying the receiver.
+func (r Router) cloneModels() map[bl.ID]tea.Model {
+	models := make(map[bl.ID]tea.Model, len(r.models)+1)
+	for k, v := range r.models {
+		models[k] = v
+	}
+	return models
+}
+
+// update sends a message to a model and stores the updated model. The models must have been cloned first.
+func (r Router) update(id bl.ID, msg tea.Msg) tea.Cmd {
+	model, cmd := r.models[id].Update(msg)
+	r.models[id] = model
+	return cmd
+}
@@ -0,0 +1,132 @@
+package bltea_test
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	bl "github.com/winder/bubblelayout"
+	"github.com/winder/bubblelayout/bltea"
+)
+
+func TestRouter(t *testing.T) {
+	l := bl.New()
+	left := l.Add("width 3!")
+	right := l.Add("")
+	status := l.Add("dock south 1!")
+
+	// the status bar is not registered, so it is not sent a message.
+	r := bltea.Router{}.
+		Register(left, sizeModel{name: "a"}).
+		Register(right, sizeModel{name: "b"})
+	assert.Equal(t, []bl.ID{left, right}, r.IDs())
+
+	r, cmd := r.Route(l.Resize(8, 4))
+	assert.Nil(t, cmd)
+	expected := map[bl.ID]bl.ComponentSizeMsg{
+		left:  {ID: left, Rect: bl.Rect{Width: 3, Height: 3}},
+		right: {ID: right, Rect: bl.Rect{X: 3, Width: 5, Height: 3}},
+	}
+	for id, size := range expected {
+		model, ok := r.Model(id)
+		require.True(t, ok)
+		assert.Equal(t, []tea.Msg{size}, model.(sizeModel).msgs, "id %d", id)
+	}
+	_, ok := r.Model(status)
+	assert.False(t, ok)
+
+	r, _ = r.Broadcast(tea.KeyMsg{Type: tea.KeyEnter})
+	for id := range expected {
+		model, _ := r.Model(id)
+		assert.Len(t, model.(sizeModel).msgs, 2, "id %d", id)
+	}
+	assert.Equal(t, map[bl.ID]string{left: "", right: ""}, r.Views())
+}
+
+func TestRouter_WindowSize(t *testing.T) {
+	l := bl.New()
+	left := l.Add("width 3!")
+	right := l.Add("")
+
+	r := bltea.Router{WindowSize: true}.
+		Register(left, sizeModel{name: "a"}).
+		Register(right, sizeModel{name: "b"})
+	r, _ = r.Route(l.Resize(8, 4))
+
+	model, _ := r.Model(left)
+	assert.Equal(t, []tea.Msg{tea.WindowSizeMsg{Width: 3, Height: 4}}, model.(sizeModel).msgs)
+	model, _ = r.Model(right)
+	assert.Equal(t, []tea.Msg{tea.WindowSizeMsg{Width: 5, Height: 4}}, model.(sizeModel).msgs)
+	assert.Equal(t, map[bl.ID]string{left: "aaa", right: "bbbbb"}, r.Views())
+}
+
+func TestRouter_Unchanged(t *testing.T) {
+	l := bl.New()
+	left := l.Add("width 3!")
+	right := l.Add("")
+
+	r := bltea.Router{WindowSize: true}.
+		Register(left, sizeModel{name: "a"}).
+		Register(right, sizeModel{name: "b"})
+	r, _ = r.Route(l.Resize(8, 4))
+	r, _ = r.Route(l.Resize(8, 4))
+
+	// the left view did not change when the window became wider.
+	r, _ = r.Route(l.Resize(10, 4))
+	model, _ := r.Model(left)
+	assert.Len(t, model.(sizeModel).msgs, 1)
+	model, _ = r.Model(right)
+	assert.Equal(t, []tea.Msg{
+		tea.WindowSizeMsg{Width: 5, Height: 4},
+		tea.WindowSizeMsg{Width: 7, Height: 4},
+	}, model.(sizeModel).msgs)
+
+	// a replaced model is sent its size.
+	r = r.Register(left, sizeModel{name: "c"})
+	r, _ = r.Route(l.Resize(10, 4))
+	model, _ = r.Model(left)
+	assert.Equal(t, []tea.Msg{tea.WindowSizeMsg{Width: 3, Height: 4}}, model.(sizeModel).msgs)
+	model, _ = r.Model(right)
+	assert.Len(t, model.(sizeModel).msgs, 2)
+}
+
+func TestRouter_Diff(t *testing.T) {
+	l := bl.New()
+	left := l.Add("width 3!")
+	right := l.Add("")
+
+	r := bltea.Router{}.
+		Register(left, sizeModel{name: "a"}).
+		Register(right, sizeModel{name: "b"})
+	r, _ = r.Route(l.Resize(8, 4))
+
+	// the size of the left view did not change, but it was moved to another layer.
+	require.NoError(t, l.Update(left, "width 3!, layer 1"))
+	r, _ = r.Route(l.Resize(8, 4))
+	model, _ := r.Model(left)
+	assert.Equal(t, []tea.Msg{
+		bl.ComponentSizeMsg{ID: left, Rect: bl.Rect{Width: 3, Height: 4}},
+		bl.ComponentSizeMsg{ID: left, Rect: bl.Rect{Width: 3, Height: 4}},
+	}, model.(sizeModel).msgs)
+	model, _ = r.Model(right)
+	assert.Len(t, model.(sizeModel).msgs, 1)
+}
+
+func TestRouter_Copy(t *testing.T) {
+	l := bl.New()
+	id := l.Add("")
+
+	r := bltea.Router{}.Register(id, sizeModel{name: "a"})
+	routed, _ := r.Route(l.Resize(8, 4))
+	broadcast, _ := routed.Broadcast(tea.KeyMsg{Type: tea.KeyEnter})
+
+	// each method returns an updated copy, the receiver is not modified.
+	model, _ := r.Model(id)
+	assert.Empty(t, model.(sizeModel).msgs)
+	model, _ = routed.Model(id)
+	assert.Len(t, model.(sizeModel).msgs, 1)
+	model, _ = broadcast.Model(id)
+	assert.Len(t, model.(sizeModel).msgs, 2)
+}
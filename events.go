@@ -0,0 +1,132 @@
+package bubblelayout
+
+import "fmt"
+
+// Axis is the direction that space is allocated in.
+type Axis string
+
+const (
+	Horizontal Axis = "horizontal"
+	Vertical   Axis = "vertical"
+)
+
+// ConstraintEvent describes a constraint that could not be satisfied when the layout was resized.
+// The events are SpaceOverallocated and UnallocatedSpace.
+type ConstraintEvent interface {
+	fmt.Stringer
+	constraintEvent()
+}
+
+// SpaceOverallocated is reported when the minimum sizes, insets and gaps need more space than is available.
+// Views are given less than their minimum size, IDs are the views which were affected.
+// This usually means that the terminal is too small to display the layout.
+type SpaceOverallocated struct {
+	Axis      Axis
+	Needed    int
+	Available int
+	IDs       []ID
+}
+
+func (e SpaceOverallocated) String() string {
+	return fmt.Sprintf("%s space overallocated: needed %d, available %d, views %v", e.Axis, e.Needed, e.Available, e.IDs)
+}
+
+func (SpaceOverallocated) constraintEvent() {}
+
+// UnallocatedSpace is reported when the maximum sizes prevent all of the available space from being used.
+// The space at the end of the layout is left empty.
+type UnallocatedSpace struct {
+	Axis        Axis
+	Unallocated int
+}
+
+func (e UnallocatedSpace) String() string {
+	return fmt.Sprintf("%s space unallocated: %d", e.Axis, e.Unallocated)
+}
+
+func (UnallocatedSpace) constraintEvent() {}
+
+// Events returns the constraints that could not be satisfied when the layout was resized.
+func (l BubbleLayoutMsg) Events() []ConstraintEvent {
+	return l.events
+}
+
+// Overallocated reports whether there was not enough space for the minimum size of every view.
+func (l BubbleLayoutMsg) Overallocated() bool {
+	for _, e := range l.events {
+		if _, ok := e.(SpaceOverallocated); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// minimums returns the minimum size of each entry, using the same rules as computeDims.
+func (pg PreferenceGroup) minimums(allocated int) []int {
+	resolved := pg.resolvePercentages(allocated)
+	ret := make([]int, len(resolved))
+	for i, p := range resolved {
+		ret[i] = p.minimum()
+	}
+	return ret
+}
+
+// constraintEvents compares the dimensions which were allocated to the preferences,
+// and reports the constraints which could not be satisfied. The reserved space is used
+// by the insets and gaps, it is removed from the size before the dimensions are allocated.
+// The views for each index are looked up with viewsAt.
+func (pg PreferenceGroup) constraintEvents(axis Axis, size, reserved int, dims []int, viewsAt func(idx int) []ID) []ConstraintEvent {
+	if len(dims) == 0 {
+		return nil
+	}
+
+	// when the insets and gaps do not fit every view is pushed out of the layout.
+	allocated := max(0, size-reserved)
+	squeezed := reserved > size
+
+	var events []ConstraintEvent
+	needed, used := reserved, 0
+	var ids []ID
+	seen := make(map[ID]struct{})
+	for idx, minimum := range pg.minimums(allocated) {
+		needed += minimum
+		used += dims[idx]
+		if dims[idx] >= minimum && !squeezed {
+			continue
+		}
+		for _, id := range viewsAt(idx) {
+			if _, ok := seen[id]; id != 0 && !ok {
+				seen[id] = struct{}{}
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	if needed > size {
+		events = append(events, SpaceOverallocated{Axis: axis, Needed: needed, Available: size, IDs: ids})
+	}
+	if used < allocated {
+		events = append(events, UnallocatedSpace{Axis: axis, Unallocated: allocated - used})
+	}
+	return events
+}
+
+// rowViews returns the views in a row of the grid.
+func (g Grid) rowViews(row int) []ID {
+	var ids []ID
+	for _, l := range g[row] {
+		ids = append(ids, l.ids()...)
+	}
+	return ids
+}
+
+// colViews returns the views in a column of the grid.
+func (g Grid) colViews(col int) []ID {
+	var ids []ID
+	for _, row := range g {
+		if col < len(row) {
+			ids = append(ids, row[col].ids()...)
+		}
+	}
+	return ids
+}
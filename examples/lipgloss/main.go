@@ -17,7 +17,6 @@ func New() tea.Model {
 	// Each model is initialized along with a layout ID.
 	title := titleModel{ID: layout.Add("height 7")}
 	description := descModel{ID: layout.Add("span 2, height 3,  wrap")}
-	dialog := dialogModel{ID: layout.Add("span 3, wrap")}
 	list1 := MakeListModel(layout.Add("height 9"), list1Title, list1)
 	list2 := MakeListModel(layout.Add("height 9"), list2Title, list2)
 	grid := MakeGridModel(layout.Add("wrap"))
@@ -25,6 +24,9 @@ func New() tea.Model {
 	history2 := MakeHistory(layout.Add("spanh 2"), historyB, lipgloss.Center, 0)
 	history3 := MakeHistory(layout.Add("spanh 2"), historyC, lipgloss.Left, 0)
 
+	// The dialog is not part of the grid, it is centered over the other views.
+	dialog := dialogModel{ID: layout.Add("pos center center 62 9")}
+
 	// Tab header and status bar are initialized as usual and docked north and south.
 	tabs := tabModel{
 		Tabs: []string{"Lip Gloss", "Blush", "Eye Shadow", "Mascara", "Foundation"},
@@ -34,42 +36,25 @@ func New() tea.Model {
 		ID: layout.Add("south 1!"),
 	}
 
-	// The models are collected into a slice for the layout model.
-	models := []tea.Model{
-		title, description,
-		dialog,
-		list1, list2, grid,
-		history1, history2, history3,
-		tabs,
-		statusbar,
-	}
-
-	// The view function glues everything together. It is called by the LayoutModel utility.
-	view := func(models []tea.Model) string {
-		return lipgloss.JoinVertical(0,
-			models[tabs.ID-1].View(),
-			lipgloss.JoinHorizontal(0,
-				models[title.ID-1].View(),
-				models[description.ID-1].View(),
-			),
-			models[dialog.ID-1].View(),
-			lipgloss.JoinHorizontal(0,
-				models[list1.ID-1].View(),
-				models[list2.ID-1].View(),
-				models[grid.ID-1].View(),
-			),
-			lipgloss.JoinHorizontal(0,
-				models[history1.ID-1].View(),
-				models[history2.ID-1].View(),
-				models[history3.ID-1].View(),
-			),
-			models[statusbar.ID-1].View(),
-		)
+	// The models are collected into a map, keyed by the ID of their view.
+	models := map[bl.ID]tea.Model{
+		title.ID:       title,
+		description.ID: description,
+		list1.ID:       list1,
+		list2.ID:       list2,
+		grid.ID:        grid,
+		history1.ID:    history1,
+		history2.ID:    history2,
+		history3.ID:    history3,
+		dialog.ID:      dialog,
+		tabs.ID:        tabs,
+		statusbar.ID:   statusbar,
 	}
 
-	// This is an example utility that calls update and converts tea.WindowSizeMsg to bl.BubbleLayoutMsg.
-	// It may be useful for real programs but is not part of the bubblelayout library.
-	return util.NewLayoutModel(models, layout, view)
+	// The dialog floats over the other views, so the views are composited by bl.Render instead of being
+	// joined together. This is an example utility that calls update and converts tea.WindowSizeMsg to
+	// bl.BubbleLayoutMsg. It may be useful for real programs but is not part of the bubblelayout library.
+	return util.NewRenderedLayoutModel(models, layout)
 }
 
 func main() {
@@ -0,0 +1,64 @@
+package util
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	bl "github.com/winder/bubblelayout"
+)
+
+type renderedLayoutModel struct {
+	models map[bl.ID]tea.Model
+	layout bl.BubbleLayout
+	// msg is the most recent layout, it is used to render the views.
+	msg bl.BubbleLayoutMsg
+}
+
+// NewRenderedLayoutModel is like NewLayoutModel, except that the views are composited with bl.Render instead
+// of a view function. This is needed when views overlap, for example a positioned dialog. Each model is
+// keyed by the ID of its view.
+func NewRenderedLayoutModel(models map[bl.ID]tea.Model, layout bl.BubbleLayout) tea.Model {
+	return renderedLayoutModel{
+		models: models,
+		layout: layout,
+	}
+}
+
+// Init does nothing, Bubble Tea sends a tea.WindowSizeMsg when the program starts which resizes the layout.
+func (m renderedLayoutModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m renderedLayoutModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		}
+	case tea.WindowSizeMsg:
+		// Convert WindowSizeMsg to BubbleLayoutMsg.
+		return m, func() tea.Msg {
+			return m.layout.Resize(msg.Width, msg.Height)
+		}
+	case bl.BubbleLayoutMsg:
+		m.msg = msg
+	}
+
+	// Dispatch to all models.
+	var commands []tea.Cmd
+	for id, model := range m.models {
+		var cmd tea.Cmd
+		m.models[id], cmd = model.Update(msg)
+		commands = append(commands, cmd)
+	}
+
+	return m, tea.Batch(commands...)
+}
+
+func (m renderedLayoutModel) View() string {
+	views := make(map[bl.ID]string, len(m.models))
+	for id, model := range m.models {
+		views[id] = model.View()
+	}
+	return bl.Render(m.msg, views)
+}
@@ -2,6 +2,7 @@ package bubblelayout
 
 import (
 	"fmt"
+	"sort"
 )
 
 type ID uint64
@@ -12,17 +13,233 @@ type Size struct {
 	Height int
 }
 
+// Rect is the area allocated for a view. X and Y are the column and row offsets
+// from the top left corner of the layout.
+type Rect struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+// Size returns the width and height of the Rect.
+func (r Rect) Size() Size {
+	return Size{Width: r.Width, Height: r.Height}
+}
+
+// inset returns the area inside of the Rect after removing the insets from each side.
+func (r Rect) inset(i Insets) Rect {
+	return Rect{
+		X:      r.X + min(i.Left, r.Width),
+		Y:      r.Y + min(i.Top, r.Height),
+		Width:  max(r.Width-i.Left-i.Right, 0),
+		Height: max(r.Height-i.Top-i.Bottom, 0),
+	}
+}
+
+// Contains reports whether the column x and row y are inside the Rect.
+func (r Rect) Contains(x, y int) bool {
+	return x >= r.X && x < r.X+r.Width && y >= r.Y && y < r.Y+r.Height
+}
+
 type BubbleLayoutMsg struct {
-	size map[ID]*Size
+	// width and height are the dimensions of the whole layout.
+	width  int
+	height int
+
+	views map[ID]*placement
+	// names are the IDs of the named views.
+	names map[string]ID
+	// order is the stacking order of the views, from the bottom to the top. Views are sorted by layer and
+	// views on the same layer are in the order that they were placed in the layout.
+	order []ID
+
+	// events are the constraints which could not be satisfied.
+	events []ConstraintEvent
 }
 
 // Size returns the size allocated for a view.
 func (l BubbleLayoutMsg) Size(id ID) (Size, error) {
-	s, ok := l.size[id]
+	r, err := l.Bounds(id)
+	if err != nil {
+		return Size{}, err
+	}
+	return r.Size(), nil
+}
+
+// SizeOf is like Size except that the view is found by its name, see AddNamed.
+func (l BubbleLayoutMsg) SizeOf(name string) (Size, error) {
+	id, ok := l.IDOf(name)
+	if !ok {
+		return Size{}, fmt.Errorf("view '%s' not registered", name)
+	}
+	return l.Size(id)
+}
+
+// IDOf returns the ID of a named view, see AddNamed.
+func (l BubbleLayoutMsg) IDOf(name string) (ID, bool) {
+	id, ok := l.names[name]
+	return id, ok
+}
+
+// Bounds returns the position and size allocated for a view. This is the outer
+// rectangle of the view, it includes the padding but not the margin.
+func (l BubbleLayoutMsg) Bounds(id ID) (Rect, error) {
+	p, ok := l.views[id]
+	if !ok {
+		return Rect{}, fmt.Errorf("view not registered")
+	}
+	return p.bounds, nil
+}
+
+// Content returns the position and size of the content area for a view. This
+// is the inner rectangle of the view, which does not include the padding.
+func (l BubbleLayoutMsg) Content(id ID) (Rect, error) {
+	p, ok := l.views[id]
+	if !ok {
+		return Rect{}, fmt.Errorf("view not registered")
+	}
+	return p.content, nil
+}
+
+// Hidden reports whether a view has been hidden. Hidden views are allocated a zero size.
+func (l BubbleLayoutMsg) Hidden(id ID) bool {
+	p, ok := l.views[id]
+	return ok && p.hidden
+}
+
+// Layer returns the layer of a view. Views on a higher layer are drawn on top of views on a lower layer.
+func (l BubbleLayoutMsg) Layer(id ID) (int, error) {
+	p, ok := l.views[id]
 	if !ok {
-		return Size{}, fmt.Errorf("view not registered")
+		return 0, fmt.Errorf("view not registered")
+	}
+	return p.layer, nil
+}
+
+// Order returns the views from the bottom to the top of the stacking order. This is the order that
+// views should be drawn in, and the reverse of the order that they should be hit tested in.
+func (l BubbleLayoutMsg) Order() []ID {
+	ret := make([]ID, 0, len(l.order))
+	for _, id := range l.order {
+		// empty cells are not views.
+		if id != 0 {
+			ret = append(ret, id)
+		}
+	}
+	return ret
+}
+
+// ComponentSizeMsg is the area allocated for a single view. It is created by BubbleLayoutMsg.Components so
+// that each model only receives its own size.
+type ComponentSizeMsg struct {
+	ID ID
+	// Rect is the content area of the view, see BubbleLayoutMsg.Content. Hidden views have a zero size.
+	Rect Rect
+}
+
+// Components splits the layout into a message for each view, ordered by ID.
+func (l BubbleLayoutMsg) Components() []ComponentSizeMsg {
+	ret := make([]ComponentSizeMsg, 0, len(l.views))
+	for id, p := range l.views {
+		// empty cells are not views.
+		if id != 0 {
+			ret = append(ret, ComponentSizeMsg{ID: id, Rect: p.content})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].ID < ret[j].ID
+	})
+	return ret
+}
+
+// Diff returns the views which were placed differently in the next layout, ordered by ID. A view has
+// changed if its position, size, border, layer or visibility changed, or if it is only in one of the
+// layouts. Views which did not change do not need to be updated or rendered again.
+func (l BubbleLayoutMsg) Diff(next BubbleLayoutMsg) []ID {
+	var ret []ID
+	for id, p := range l.views {
+		if q, ok := next.views[id]; !ok || *p != *q {
+			ret = append(ret, id)
+		}
+	}
+	for id := range next.views {
+		if _, ok := l.views[id]; !ok {
+			ret = append(ret, id)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i] < ret[j]
+	})
+	// empty cells are not views.
+	if len(ret) > 0 && ret[0] == 0 {
+		ret = ret[1:]
+	}
+	return ret
+}
+
+// sortLayers stably sorts the order by layer.
+func (l *BubbleLayoutMsg) sortLayers() {
+	layer := func(id ID) int {
+		if p, ok := l.views[id]; ok {
+			return p.layer
+		}
+		return 0
+	}
+	sort.SliceStable(l.order, func(i, j int) bool {
+		return layer(l.order[i]) < layer(l.order[j])
+	})
+}
+
+// placement is the area allocated for a view.
+type placement struct {
+	// hidden indicates that the view is not visible.
+	hidden bool
+
+	// bounds is the area of the cell without the margin.
+	bounds Rect
+	// frame is the area that the border is drawn around. It may overlap
+	// neighboring views so that adjacent borders can share an edge.
+	frame Rect
+	// border is the style of the border, it is empty if there is no border.
+	border BorderStyle
+	// content is the area inside of the border and padding.
+	content Rect
+
+	// layer is the position of the view in the stacking order.
+	layer int
+	// floating indicates that the view is not part of the grid, it covers the views beneath it.
+	floating bool
+}
+
+// translate returns the placement moved by x columns and y rows.
+func (p placement) translate(x, y int) placement {
+	move := func(r Rect) Rect {
+		r.X += x
+		r.Y += y
+		return r
+	}
+	p.bounds = move(p.bounds)
+	p.frame = move(p.frame)
+	p.content = move(p.content)
+	return p
+}
+
+// HitTest returns the view which occupies the column x and row y, for example
+// the coordinates of a tea.MouseMsg. The second return value is false when the
+// coordinate is outside the layout or in an empty cell.
+func (l BubbleLayoutMsg) HitTest(x, y int) (ID, bool) {
+	// views placed later are drawn on top.
+	for i := len(l.order) - 1; i >= 0; i-- {
+		id := l.order[i]
+		if id == 0 {
+			continue
+		}
+		if l.views[id].bounds.Contains(x, y) {
+			return id, true
+		}
 	}
-	return *s, nil
+	return 0, false
 }
 
 const (
@@ -34,182 +251,346 @@ const (
 
 type PreferenceGroup []BoundSize
 
-// computeDims takes a list of BoundSizes and an allocated size and returns the actual size that should be allocated to each component.
-// TODO: detect if the minimums add up to more than the allocated size and generate a constraint violation error.
-//
-//	pass 1: allocate minimums. Priority is given left to right.
-//	        -> if minimums fill up the allocated size, everything else remains 0.
-//	pass 2: evenly expand up to min(preferred, max), or an even split.
-//	        TODO: This is done in a loop. Can it be done in a single iteration?
-//	pass 3a: If "grow" is used, allocate remaining space to growers.
-//	pass 3b: Otherwise, allocate remaining space to "max" or cells with no max.
-//	        TODO: This is done in a loop. Can it be done in a single iteration?
-//
-//	TODO: Grow priorities.
-//	TODO: What does it mean to have Grow and Max? Can it go over the Max?
-func (pg PreferenceGroup) computeDims(allocated int) []int {
-	if len(pg) == 0 {
-		return nil
+// DefaultWeight is the grow and shrink weight used when one is not provided.
+const DefaultWeight = 100
+
+// DefaultPriority is the grow and shrink priority used when one is not provided.
+const DefaultPriority = 100
+
+// NoShrink is a shrink weight which prevents a size from shrinking below its preferred size.
+const NoShrink = -1
+
+// target is the size that should be reached before the remaining space is allocated to growers.
+// This is the preferred size, or the max if there is no preference.
+func (b BoundSize) target() int {
+	if b.Preferred != 0 {
+		return b.Preferred
+	}
+	return b.Max
+}
+
+// minimum is the size allocated before anything else. Sizes which do not shrink use their target.
+func (b BoundSize) minimum() int {
+	if b.ShrinkWeight == NoShrink {
+		return max(b.Min, b.target())
+	}
+	return b.Min
+}
+
+// shrinkWeight returns the shrink weight, replacing the zero value with the default.
+func (b BoundSize) shrinkWeight() int {
+	switch b.ShrinkWeight {
+	case 0:
+		return DefaultWeight
+	case NoShrink:
+		return 0
+	default:
+		return b.ShrinkWeight
 	}
+}
 
-	// cache some useful things
-	hasMin := make(map[int]struct{})
-	totalMin := 0
-	hasMax := make(map[int]struct{})
-	hasPref := make(map[int]struct{})
-	// totalToPref is the addition needed to get from Min to Preferred.
-	totalToPref := 0
-	// totalToMax is the addition needed to get from Min/Preferred to Max
-	totalToMax := 0
-	hasGrow := make(map[int]struct{})
-	noGrowNoPref := make(map[int]struct{})
+// shrinkPriority returns the shrink priority, replacing the zero value with the default.
+func (b BoundSize) shrinkPriority() int {
+	if b.ShrinkPriority == 0 {
+		return DefaultPriority
+	}
+	return b.ShrinkPriority
+}
 
+// shrinkGroups returns the indices grouped by shrink priority. Groups are sorted
+// from the lowest to highest priority, indices within a group are in order.
+func (pg PreferenceGroup) shrinkGroups() [][]int {
+	byPriority := make(map[int][]int)
+	var priorities []int
 	for idx, p := range pg {
-		if p.Min != 0 {
-			totalMin += p.Min
-			hasMin[idx] = struct{}{}
+		prio := p.shrinkPriority()
+		if _, ok := byPriority[prio]; !ok {
+			priorities = append(priorities, prio)
 		}
-		if p.Max != 0 {
-			hasMax[idx] = struct{}{}
-			if p.Preferred != 0 {
-				// if there is no preference, the max is already included in 'totalToPref'
-				totalToMax += p.Max - p.Preferred
-			}
+		byPriority[prio] = append(byPriority[prio], idx)
+	}
+	sort.Ints(priorities)
+
+	groups := make([][]int, 0, len(priorities))
+	for _, prio := range priorities {
+		groups = append(groups, byPriority[prio])
+	}
+	return groups
+}
+
+// growWeight returns the grow weight, replacing the zero value with the default.
+func (b BoundSize) growWeight() int {
+	if b.GrowWeight == 0 {
+		return DefaultWeight
+	}
+	return b.GrowWeight
+}
+
+// growPriority returns the grow priority, replacing the zero value with the default.
+func (b BoundSize) growPriority() int {
+	if b.GrowPriority == 0 {
+		return DefaultPriority
+	}
+	return b.GrowPriority
+}
+
+// canGrow returns true if the size is below its max.
+func (b BoundSize) canGrow(dim int) bool {
+	return b.Max == 0 || dim < b.Max
+}
+
+// growGroups returns the indices which should receive extra space, grouped by grow priority.
+// Groups are sorted from the highest to lowest priority, indices within a group are in order.
+// If there are no growers, cells with no max, preference or grow share the extra space evenly.
+func (pg PreferenceGroup) growGroups(dims []int) [][]int {
+	byPriority := make(map[int][]int)
+	var priorities []int
+	for idx, p := range pg {
+		if !p.Grow || !p.canGrow(dims[idx]) {
+			continue
 		}
-		if p.Preferred != 0 || (p.Preferred == 0 && p.Max != 0) {
-			hasPref[idx] = struct{}{}
-			totalToPref += max(p.Preferred, p.Max) - p.Min
+		prio := p.growPriority()
+		if _, ok := byPriority[prio]; !ok {
+			priorities = append(priorities, prio)
 		}
-		if p.Grow {
-			hasGrow[idx] = struct{}{}
+		byPriority[prio] = append(byPriority[prio], idx)
+	}
+
+	if len(priorities) == 0 {
+		var noGrowNoPref []int
+		for idx, p := range pg {
+			if p.Max == 0 && p.Preferred == 0 && !p.Grow {
+				noGrowNoPref = append(noGrowNoPref, idx)
+			}
 		}
-		if p.Max == 0 && p.Preferred == 0 && !p.Grow {
-			noGrowNoPref[idx] = struct{}{}
+		if len(noGrowNoPref) == 0 {
+			return nil
 		}
+		return [][]int{noGrowNoPref}
 	}
 
-	dims := make([]int, len(pg))
-	remainder := allocated
-	// number of spots left to compute
-	numToCompute := len(pg)
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+	groups := make([][]int, 0, len(priorities))
+	for _, prio := range priorities {
+		groups = append(groups, byPriority[prio])
+	}
+	return groups
+}
 
-	// Pass 1: allocate minimums, exit early if not enough space.
-	// don't range over min to avoid nondeterminism
-	if len(hasMin) > 0 {
-		for idx, p := range pg {
-			if p.Min != 0 {
-				sz := min(p.Min, remainder)
-				dims[idx] = sz
-				remainder -= sz
-				if remainder == 0 {
-					return dims
-				}
+// growGroup splits the remainder amongst a group in proportion to the grow weights, without
+// going over the max. The space that could not be allocated is returned.
+func (pg PreferenceGroup) growGroup(group []int, dims []int, remainder int) int {
+	for remainder > 0 {
+		var active []int
+		totalWeight := 0
+		for _, idx := range group {
+			if pg[idx].canGrow(dims[idx]) {
+				active = append(active, idx)
+				// cells without grow use the default weight, so they share the space evenly.
+				totalWeight += pg[idx].growWeight()
 			}
 		}
-	}
+		if len(active) == 0 {
+			return remainder
+		}
 
-	// pass 2: even split, stopping at preferred (or max).
-	growToPrefferred := func() {
-		evenSplit := remainder / numToCompute
-		// reallocate a larger even split if totalToPref is reached.
-		if evenSplit*len(hasPref) >= totalToPref {
-			if numToCompute == len(hasPref) {
-				// everyone gets their preference. Set a large evenSplit to avoid a divide by zero.
-				// this is a special case that can avoid extra iterations.
-				evenSplit = 100000
-			} else {
-				evenSplit = (remainder - totalToPref) / (numToCompute - len(hasPref))
+		allocated := 0
+		for _, idx := range active {
+			sz := remainder * pg[idx].growWeight() / totalWeight
+			if pg[idx].Max != 0 {
+				sz = min(sz, pg[idx].Max-dims[idx])
 			}
+			dims[idx] += sz
+			allocated += sz
 		}
-		for idx, p := range pg {
-			var sz int
-			if _, ok := hasPref[idx]; ok {
-				if p.Preferred != 0 {
-					// stop at preferred if needed
-					sz = min(p.Preferred-dims[idx], evenSplit)
-					if sz+dims[idx] >= p.Preferred {
-						delete(hasPref, idx)
-					}
-				} else if p.Max != 0 {
-					// stop at max if there is no preference
-					sz = min(p.Max-dims[idx], evenSplit)
-					if sz+dims[idx] >= p.Max {
-						// If max is reached, that item is done. Remove it from numToCompute.
-						numToCompute--
-						// it is done, remove from lists for later
-						delete(hasGrow, idx)
-						delete(hasMax, idx)
-						delete(hasPref, idx)
-					}
+		remainder -= allocated
+
+		// the split was rounded down, hand out the rest one at a time.
+		if allocated == 0 {
+			for _, idx := range active {
+				if remainder == 0 {
+					break
 				}
-				dims[idx] += sz
-				remainder -= sz
+				dims[idx]++
+				remainder--
 			}
 		}
 	}
+	return remainder
+}
+
+// resolvePercentages returns a copy of the PreferenceGroup where percentages of the
+// allocated size are combined with the other sizes.
+func (pg PreferenceGroup) resolvePercentages(allocated int) PreferenceGroup {
+	ret := make(PreferenceGroup, len(pg))
+	copy(ret, pg)
+	allocated = max(allocated, 0)
+	for i, b := range ret {
+		b.Min = max(b.Min, b.MinPercent*allocated/100)
+		b.Preferred = max(b.Preferred, b.PreferredPercent*allocated/100)
+		if b.MaxPercent != 0 {
+			// a max of 0 would not have a limit, so there is at least 1.
+			b.Max = minNonZero(b.Max, max(b.MaxPercent*allocated/100, 1))
+		}
+		ret[i] = b
+	}
+	return ret
+}
+
+// computeDims takes a list of BoundSizes and an allocated size and returns the actual size that should be allocated to each component.
+// If the minimums add up to more than the allocated size, the components which are allocated last are truncated. This is reported
+// by constraintEvents.
+//
+//	pass 1: allocate minimums. Priority is given to the lowest shrink priority, then left to right.
+//	        -> if minimums fill up the allocated size, everything else remains 0.
+//	pass 2: expand up to preferred (or max). When there is not enough room, the highest shrink
+//	        priority gives up space first. Sizes with the same priority are evenly expanded, then
+//	        reduced in proportion to their shrink weight.
+//	pass 3a: If "grow" is used, allocate remaining space to growers. The highest grow priority
+//	        grows to its max before any other grower. Sizes with the same priority grow in
+//	        proportion to their grow weight.
+//	pass 3b: Otherwise, allocate remaining space evenly to cells with no preference or max.
+//
+//	TODO: What does it mean to have Grow and Max? Can it go over the Max?
+func (pg PreferenceGroup) computeDims(allocated int) []int {
+	if len(pg) == 0 {
+		return nil
+	}
+
+	pg = pg.resolvePercentages(allocated)
+	dims := make([]int, len(pg))
+	remainder := max(allocated, 0)
+
+	// Pass 1: allocate minimums, exit early if not enough space.
+	for _, group := range pg.shrinkGroups() {
+		for _, idx := range group {
+			sz := min(pg[idx].minimum(), remainder)
+			dims[idx] = sz
+			remainder -= sz
+		}
+	}
+	if remainder == 0 {
+		return dims
+	}
 
-	for len(hasPref) > 0 && remainder > 0 && remainder > numToCompute {
-		growToPrefferred()
+	// Pass 2: expand to preferred, shrinking by priority and weight if there is not enough room.
+	for _, group := range pg.shrinkGroups() {
+		need := 0
+		for _, idx := range group {
+			need += max(pg[idx].target()-dims[idx], 0)
+		}
+		if need > remainder {
+			pg.shrinkToFit(group, dims, remainder)
+			return dims
+		}
+		for _, idx := range group {
+			dims[idx] = max(dims[idx], pg[idx].target())
+		}
+		remainder -= need
 	}
 
-	// Check if we're done. Either there is no more space, or nothing left to grow.
-	if remainder == 0 || numToCompute == 0 {
+	// Check if we're done.
+	if remainder == 0 {
 		return dims
 	}
 
-	// pass 3: even split amongst growers OR non-growers with no max.
-	growToMax := func() []int {
-		var set map[int]struct{}
-		var evenSplit int
-		if len(hasGrow) > 0 {
-			evenSplit = remainder / len(hasGrow)
-			set = hasGrow
-		} else if len(noGrowNoPref) > 0 {
-			evenSplit = remainder / len(noGrowNoPref)
-			set = noGrowNoPref
+	// pass 3: allocate the remaining space to growers, by priority and weight. Without
+	// growers the remaining space is split evenly amongst cells with no preference.
+	for _, group := range pg.growGroups(dims) {
+		remainder = pg.growGroup(group, dims, remainder)
+		if remainder == 0 {
+			break
 		}
+	}
 
-		remainderList := make([]int, 0, len(set))
-		for idx := range pg {
-			if _, ok := set[idx]; !ok {
-				continue
-			}
-			var sz int
-			if pg[idx].Max != 0 {
-				sz = min(pg[idx].Max-dims[idx], evenSplit)
-				if sz+dims[idx] >= pg[idx].Max {
-					// it is done
-					delete(hasGrow, idx)
-					delete(hasMax, idx)
-				}
-			} else {
-				sz = evenSplit
-			}
-			dims[idx] += sz
-			remainder -= sz
-			if pg[idx].Max != dims[idx] {
-				remainderList = append(remainderList, idx)
-			}
+	return dims
+}
+
+// shrinkToFit expands a group of sizes towards their target when there is not enough room for all of them.
+//
+// This is done by finding the largest level where every size is given min(target, level). It is an even
+// split where nothing goes over its target. Shrink weights are applied by reducing each size from its
+// target in proportion to the weight: a size with twice the weight loses twice as much space.
+func (pg PreferenceGroup) shrinkToFit(group []int, dims []int, space int) {
+	// the smallest weight is used as a reference, so that equal weights are a plain even split.
+	refWeight := 0
+	maxTarget := 0
+	for _, idx := range group {
+		if w := pg[idx].shrinkWeight(); w > 0 && (refWeight == 0 || w < refWeight) {
+			refWeight = w
+		}
+		maxTarget = max(maxTarget, pg[idx].target())
+	}
+	if refWeight == 0 {
+		refWeight = DefaultWeight
+	}
+
+	base := make([]int, len(group))
+	for i, idx := range group {
+		base[i] = dims[idx]
+	}
+	sizeAt := func(i, level int) int {
+		target := pg[group[i]].target()
+		if level >= target {
+			return max(base[i], target)
+		}
+		return max(base[i], target-pg[group[i]].shrinkWeight()*(target-level)/refWeight)
+	}
+	usedAt := func(level int) int {
+		used := 0
+		for i := range group {
+			used += sizeAt(i, level) - base[i]
 		}
-		return remainderList
+		return used
 	}
 
-	remainderList := growToMax()
-	for len(remainderList) > 0 && len(remainderList) < remainder {
-		remainderList = growToMax()
+	// find the largest level which fits, usedAt grows with the level.
+	level := sort.Search(maxTarget+1, func(level int) bool {
+		return usedAt(level) > space
+	}) - 1
+
+	for i, idx := range group {
+		dims[idx] = sizeAt(i, level)
 	}
 
-	// allocate the remainder if any
-	if remainder > 0 {
-		for _, idx := range remainderList {
-			dims[idx] += 1
-			remainder -= 1
-			if remainder == 0 {
-				break
+	// hand out whatever is left one at a time, left to right.
+	remainder := space - usedAt(level)
+	for remainder > 0 {
+		progress := false
+		for _, idx := range group {
+			if remainder > 0 && dims[idx] < pg[idx].target() {
+				dims[idx]++
+				remainder--
+				progress = true
 			}
 		}
+		if !progress {
+			return
+		}
+	}
+}
+
+// computeVisibleDims is like computeDims, except that collapsed entries are not allocated any space.
+func (pg PreferenceGroup) computeVisibleDims(allocated int, collapsed []bool) []int {
+	var visible PreferenceGroup
+	for idx, p := range pg {
+		if idx >= len(collapsed) || !collapsed[idx] {
+			visible = append(visible, p)
+		}
+	}
+	if len(visible) == len(pg) {
+		return pg.computeDims(allocated)
 	}
 
+	visibleDims := visible.computeDims(allocated)
+	dims := make([]int, len(pg))
+	next := 0
+	for idx := range pg {
+		if idx >= len(collapsed) || !collapsed[idx] {
+			dims[idx] = visibleDims[next]
+			next++
+		}
+	}
 	return dims
 }
 
@@ -221,38 +602,203 @@ type BoundSize struct {
 	Preferred int
 	Max       int
 	Grow      bool
+
+	// MinPercent, PreferredPercent and MaxPercent are sizes relative to the allocated space, from 0 to 100.
+	// They are resolved when the layout is resized and combined with the other sizes: the larger minimum
+	// and preferred size and the smaller maximum size are used.
+	MinPercent       int
+	PreferredPercent int
+	MaxPercent       int
+
+	// GrowWeight is how much of the extra space is allocated to the size, relative to other sizes
+	// with the same GrowPriority. Zero uses DefaultWeight. It is only used when Grow is set.
+	GrowWeight int
+	// GrowPriority orders which sizes receive extra space first. Sizes with a higher priority grow
+	// to their max before any lower priority size grows. Zero uses DefaultPriority.
+	GrowPriority int
+
+	// ShrinkWeight is how readily the size is reduced from its preferred size when there is not enough
+	// space, relative to other sizes with the same ShrinkPriority. Zero uses DefaultWeight, NoShrink
+	// prevents the size from being reduced.
+	ShrinkWeight int
+	// ShrinkPriority orders which sizes are reduced first when there is not enough space. Sizes with a
+	// higher priority are reduced to their minimum before any lower priority size is reduced. Zero uses
+	// DefaultPriority.
+	ShrinkPriority int
 }
 
 type Grid [][]layout
 
-func (g Grid) makeMessage(wDims, hDims []int) BubbleLayoutMsg {
+// offsets converts a list of dimensions into the starting offset of each one.
+// The offsets begin at start, and before is the gap in front of each dimension.
+func offsets(dims, before []int, start int) []int {
+	ret := make([]int, len(dims))
+	for i := range dims {
+		start += before[i]
+		ret[i] = start
+		start += dims[i]
+	}
+	return ret
+}
+
+// clampOffsets moves the offsets which are past the end of the layout to its edge. This happens when the
+// insets and gaps are larger than the layout.
+func clampOffsets(offsets []int, size int) []int {
+	for i := range offsets {
+		offsets[i] = min(offsets[i], max(0, size))
+	}
+	return offsets
+}
+
+// makeMessage places the views in the grid. Views with a maximum size smaller than their cell are aligned
+// within it, the alignments are from the cells before their spans were expanded.
+func (g Grid) makeMessage(wDims, hDims, xOffsets, yOffsets []int, alignments map[ID]cellAlignment) BubbleLayoutMsg {
 	msg := BubbleLayoutMsg{
-		size: make(map[ID]*Size),
+		views: make(map[ID]*placement),
 	}
+	allocated := make(map[ID]*Rect)
+	layouts := make(map[ID]layout)
 
-	// to avoid double counting spanning cells, keep track of which rows and column was used to process a layout size.
-	idRow := make(map[ID]int)
-	idCol := make(map[ID]int)
+	// Cells are visited left to right and top to bottom, so the first visit is always the top left corner.
+	// Spanning cells extend to the far edge of the last row and column they are in, including the gaps.
 	for rowIdx, row := range g {
 		for colIdx, l := range row {
-			if _, ok := msg.size[l.id]; !ok {
-				msg.size[l.id] = &Size{}
+			r, ok := allocated[l.id]
+			if !ok {
+				r = &Rect{X: xOffsets[colIdx], Y: yOffsets[rowIdx]}
+				allocated[l.id] = r
+				layouts[l.id] = l
+				msg.order = append(msg.order, l.id)
 			}
-			if _, ok := idRow[l.id]; !ok {
-				idRow[l.id] = rowIdx
+			r.Width = max(r.Width, xOffsets[colIdx]+wDims[colIdx]-r.X)
+			r.Height = max(r.Height, yOffsets[rowIdx]+hDims[rowIdx]-r.Y)
+		}
+	}
+
+	msg.order = splitAllocations(msg.order, allocated, layouts)
+
+	for id, r := range allocated {
+		l := layouts[id]
+		if l.hidden {
+			// hidden views keep their position, but are not given any space.
+			hidden := Rect{X: r.X, Y: r.Y}
+			msg.views[id] = &placement{hidden: true, bounds: hidden, frame: hidden, content: hidden, layer: l.Cell.Layer}
+			continue
+		}
+		bounds := r.inset(l.Margin)
+		if a, ok := alignments[id]; ok {
+			bounds = a.align(bounds)
+		}
+		msg.views[id] = &placement{
+			bounds: bounds,
+			frame:  bounds,
+			border: l.Cell.Border,
+			layer:  l.Cell.Layer,
+		}
+	}
+	msg.collapseBorders()
+	for id, p := range msg.views {
+		if p.hidden {
+			continue
+		}
+		inner := p.frame
+		if p.border != BorderNone {
+			inner = inner.inset(Insets{Top: 1, Left: 1, Bottom: 1, Right: 1})
+		}
+		p.content = inner.inset(layouts[id].Padding)
+	}
+	return msg
+}
+
+// placePositioned adds the positioned views to the message, after the grid so that they are on top of it
+// unless they are on a lower layer.
+func (l *BubbleLayoutMsg) placePositioned(positioned []layout) {
+	for _, p := range positioned {
+		r := p.Pos.rect(l.width, l.height)
+		l.order = append(l.order, p.id)
+		if p.hidden {
+			hidden := Rect{X: r.X, Y: r.Y}
+			l.views[p.id] = &placement{hidden: true, bounds: hidden, frame: hidden, content: hidden, layer: p.Pos.Layer}
+			continue
+		}
+		content := r
+		if p.Pos.Border != BorderNone {
+			content = content.inset(Insets{Top: 1, Left: 1, Bottom: 1, Right: 1})
+		}
+		l.views[p.id] = &placement{
+			bounds:   r,
+			frame:    r,
+			content:  content,
+			border:   p.Pos.Border,
+			layer:    p.Pos.Layer,
+			floating: true,
+		}
+	}
+}
+
+// splitAllocations divides the area allocated to each split cell between its views, from left
+// to right or top to bottom. The views are added to the order and replace the split cell in
+// allocated and layouts.
+func splitAllocations(order []ID, allocated map[ID]*Rect, layouts map[ID]layout) []ID {
+	ret := make([]ID, 0, len(order))
+	for _, id := range order {
+		l := layouts[id]
+		if len(l.split) == 0 {
+			ret = append(ret, id)
+			continue
+		}
+		r := *allocated[id]
+		pg, collapsed := splitBounds(l.split, l.splitVertical)
+		if l.splitVertical {
+			y := r.Y
+			for i, height := range pg.computeVisibleDims(r.Height, collapsed) {
+				m := l.split[i]
+				allocated[m.id] = &Rect{X: r.X, Y: y, Width: r.Width, Height: height}
+				layouts[m.id] = m
+				ret = append(ret, m.id)
+				y += height
 			}
-			if _, ok := idCol[l.id]; !ok {
-				idCol[l.id] = colIdx
+			continue
+		}
+		x := r.X
+		for i, width := range pg.computeVisibleDims(r.Width, collapsed) {
+			m := l.split[i]
+			allocated[m.id] = &Rect{X: x, Y: r.Y, Width: width, Height: r.Height}
+			layouts[m.id] = m
+			ret = append(ret, m.id)
+			x += width
+		}
+	}
+	return ret
+}
+
+// collapseBorders extends the frame of bordered views by one column or row when
+// they are directly next to another bordered view. The neighbors then share an
+// edge instead of drawing two borders side by side.
+func (l BubbleLayoutMsg) collapseBorders() {
+	for _, id := range l.order {
+		p := l.views[id]
+		if p.border == BorderNone {
+			continue
+		}
+		for _, otherID := range l.order {
+			other := l.views[otherID]
+			if otherID == id || other.border == BorderNone {
+				continue
 			}
-			if idRow[l.id] == rowIdx {
-				msg.size[l.id].Width += wDims[colIdx]
+			b, o := p.bounds, other.bounds
+			overlapX := b.X < o.X+o.Width && o.X < b.X+b.Width
+			overlapY := b.Y < o.Y+o.Height && o.Y < b.Y+b.Height
+			if overlapY && o.X+o.Width == b.X && p.frame.X == b.X {
+				p.frame.X--
+				p.frame.Width++
 			}
-			if idCol[l.id] == colIdx {
-				msg.size[l.id].Height += hDims[rowIdx]
+			if overlapX && o.Y+o.Height == b.Y && p.frame.Y == b.Y {
+				p.frame.Y--
+				p.frame.Height++
 			}
 		}
 	}
-	return msg
 }
 
 // TODO:
@@ -262,12 +808,86 @@ func (g Grid) makeMessage(wDims, hDims []int) BubbleLayoutMsg {
 // layout holds the Cell or Dock information in addition to the ID.
 type layout struct {
 	id ID
+	// name identifies the view in the BubbleLayoutMsg, it is optional.
+	name string
 
 	// wrap indicates that the grid should wrap to the next row after this Layout.
 	wrap bool
 
+	// hidden indicates that the view is not visible, hideMode controls whether it still takes up space.
+	hidden   bool
+	hideMode HideMode
+
+	// joinSplit indicates that the layout shares the cell of the previous layout, which has a Split.
+	joinSplit bool
+	// split is every view in a split cell, it is set by mergeSplits.
+	split []layout
+	// splitVertical indicates that the views in a split cell are placed top to bottom.
+	splitVertical bool
+
+	// positioned indicates that the view is placed with Pos instead of in the grid.
+	positioned bool
+
 	Cell
 	Dock
+	Pos
+}
+
+// Alignment positions a view within its cell when the view is smaller than the cell.
+type Alignment string
+
+const (
+	AlignLeft   Alignment = "left"
+	AlignCenter Alignment = "center"
+	AlignRight  Alignment = "right"
+	AlignTop    Alignment = "top"
+	AlignBottom Alignment = "bottom"
+)
+
+// offset returns the position of a view within the free space of its cell.
+func (a Alignment) offset(free int) int {
+	switch a {
+	case AlignCenter:
+		return free / 2
+	case AlignRight, AlignBottom:
+		return free
+	default:
+		return 0
+	}
+}
+
+// cellAlignment is the maximum size of a view and its position within its cell.
+type cellAlignment struct {
+	maxWidth  int
+	maxHeight int
+	alignX    Alignment
+	alignY    Alignment
+}
+
+// align returns the part of the bounds which is used by the view.
+func (a cellAlignment) align(r Rect) Rect {
+	if a.maxWidth > 0 && r.Width > a.maxWidth {
+		r.X += a.alignX.offset(r.Width - a.maxWidth)
+		r.Width = a.maxWidth
+	}
+	if a.maxHeight > 0 && r.Height > a.maxHeight {
+		r.Y += a.alignY.offset(r.Height - a.maxHeight)
+		r.Height = a.maxHeight
+	}
+	return r
+}
+
+// viewMax returns the maximum size of a view, without its margin. Percentages are of the allocated size.
+// The view is never smaller than its padding and border.
+func viewMax(maximum, percent, allocated, margin, padding int) int {
+	if percent != 0 {
+		// a max of 0 would not have a limit, so there is at least 1.
+		maximum = minNonZero(maximum, max(percent*allocated/100-margin, 1))
+	}
+	if maximum == 0 {
+		return 0
+	}
+	return max(maximum, padding)
 }
 
 // Cell defines the size and position that should be allocated for a view.
@@ -277,6 +897,11 @@ type Cell struct {
 	// SpanHeight defines the number of rows that the view should span. Defaults to 1.
 	SpanHeight int
 
+	// Split places this view and the next Split-1 views in the same cell. The views are placed left to right
+	// inside the cell and each is allocated its own width, independent of the grid columns. Spans on the
+	// views which join the cell are ignored. A wrap ends the split early.
+	Split int
+
 	// MinWidth overrides the minimum width that should be allocated for the view.
 	MinWidth int
 	// PreferredWidth overrides the Preferred width that should be allocated for the view.
@@ -284,6 +909,13 @@ type Cell struct {
 	// MaxWidth overrides the maximum width that should be allocated for the view.
 	MaxWidth int
 
+	// MinWidthPercent is the minimum width as a percentage of the layout width.
+	MinWidthPercent int
+	// PreferredWidthPercent is the preferred width as a percentage of the layout width.
+	PreferredWidthPercent int
+	// MaxWidthPercent is the maximum width as a percentage of the layout width.
+	MaxWidthPercent int
+
 	// MinHeight overrides the minimum height that should be allocated for the view.
 	MinHeight int
 	// PreferredHeight overrides the Preferred height that should be allocated for the view.
@@ -291,174 +923,1537 @@ type Cell struct {
 	// MaxHeight overrides the maximum height that should be allocated for the view.
 	MaxHeight int
 
+	// MinHeightPercent is the minimum height as a percentage of the layout height.
+	MinHeightPercent int
+	// PreferredHeightPercent is the preferred height as a percentage of the layout height.
+	PreferredHeightPercent int
+	// MaxHeightPercent is the maximum height as a percentage of the layout height.
+	MaxHeightPercent int
+
 	// GrowWidth indicates that the horizontal size should be maximized.
 	GrowWidth bool
 	// GrowHeight indicates that the vertical size should be maximized.
 	GrowHeight bool
 
+	// GrowWidthWeight is the grow weight for the width, see BoundSize.GrowWeight.
+	GrowWidthWeight int
+	// GrowHeightWeight is the grow weight for the height, see BoundSize.GrowWeight.
+	GrowHeightWeight int
+	// GrowWidthPriority is the grow priority for the width, see BoundSize.GrowPriority.
+	GrowWidthPriority int
+	// GrowHeightPriority is the grow priority for the height, see BoundSize.GrowPriority.
+	GrowHeightPriority int
+
+	// ShrinkWidthWeight is the shrink weight for the width, see BoundSize.ShrinkWeight.
+	ShrinkWidthWeight int
+	// ShrinkHeightWeight is the shrink weight for the height, see BoundSize.ShrinkWeight.
+	ShrinkHeightWeight int
+	// ShrinkWidthPriority is the shrink priority for the width, see BoundSize.ShrinkPriority.
+	ShrinkWidthPriority int
+	// ShrinkHeightPriority is the shrink priority for the height, see BoundSize.ShrinkPriority.
+	ShrinkHeightPriority int
+
+	// Padding is the space reserved between the edge of the view and its content.
+	Padding Insets
+	// Margin is the space reserved around the view, separating it from neighboring cells.
+	Margin Insets
+
+	// Border draws a border around the view, reserving one row or column on each side.
+	Border BorderStyle
+
+	// Layer is the position of the view in the stacking order, views on a higher layer are on top.
+	// Defaults to 0, negative layers are below the default layer.
+	Layer int
+
+	// SizeGroup gives the view the same width and height preferences as the other views in the group, even
+	// if they are in different rows and columns. SizeGroupWidth and SizeGroupHeight are the same, for only the
+	// width or height. They take precedence over SizeGroup.
+	SizeGroup       string
+	SizeGroupWidth  string
+	SizeGroupHeight string
+
+	// AlignX and AlignY position the view within its cell when the cell is larger than the MaxWidth or
+	// MaxHeight of the view. When an alignment is set the maximum size no longer limits the column or row,
+	// so the cell can be larger than the view. Defaults to AlignLeft and AlignTop.
+	AlignX Alignment
+	AlignY Alignment
+
 	// wDuplicate is used as part of horizontal spanning for calculating dimensions.
 	wDuplicate bool
 	// hDuplicate is used as part of vertical spanning for calculating dimensions.
 	hDuplicate bool
 }
 
-// Dock defines a component that should span an entire side of the layout.
-type Dock struct {
-	// Cardinal indicates which side of the layout the view should be docked to.
-	Cardinal Cardinal
+// BorderStyle selects the characters used to draw a border.
+type BorderStyle string
 
-	// Min overrides the minimum width or height that should be allocated for the view.
-	Min int
+const (
+	BorderNone    BorderStyle = ""
+	BorderNormal  BorderStyle = "normal"
+	BorderRounded BorderStyle = "rounded"
+	BorderThick   BorderStyle = "thick"
+	BorderDouble  BorderStyle = "double"
+)
+
+// size returns the number of rows or columns used by the border on each side.
+func (b BorderStyle) size() int {
+	if b == BorderNone {
+		return 0
+	}
+	return 1
+}
+
+// Insets is the amount of space on each side of a rectangle.
+type Insets struct {
+	Top    int
+	Left   int
+	Bottom int
+	Right  int
+}
+
+// horizontal returns the total horizontal space of the insets.
+func (i Insets) horizontal() int {
+	return i.Left + i.Right
+}
+
+// vertical returns the total vertical space of the insets.
+func (i Insets) vertical() int {
+	return i.Top + i.Bottom
+}
+
+// transpose swaps the horizontal and vertical insets.
+func (i Insets) transpose() Insets {
+	return Insets{Top: i.Left, Left: i.Top, Bottom: i.Right, Right: i.Bottom}
+}
+
+// HideMode controls how the space of a hidden view is handled.
+type HideMode int
+
+const (
+	// HideModeReserve keeps the space of hidden views as if they were visible.
+	HideModeReserve HideMode = iota
+	// HideModeCollapse ignores the size preferences of hidden views. Rows and
+	// columns which only contain hidden views collapse to nothing.
+	HideModeCollapse
+)
+
+// ids returns the ID of the layout, or the IDs of every view if it is a split cell.
+func (l layout) ids() []ID {
+	if len(l.split) == 0 {
+		return []ID{l.id}
+	}
+	ids := make([]ID, len(l.split))
+	for i, m := range l.split {
+		ids[i] = m.id
+	}
+	return ids
+}
+
+// collapsed reports whether the layout should be removed from the size calculations.
+func (l layout) collapsed() bool {
+	return l.hidden && l.hideMode == HideModeCollapse
+}
+
+// CornerPolicy decides which dock owns a corner of the layout, where a horizontal and a vertical dock meet.
+// The owner spans the entire edge and the other dock stops before the corner.
+type CornerPolicy string
+
+const (
+	// CornersByOrder gives the corners to the dock which was added last. This is the default.
+	CornersByOrder CornerPolicy = ""
+	// CornersOwn gives the corners to the dock, unless the other dock also owns its corners.
+	CornersOwn CornerPolicy = "own"
+	// CornersYield gives the corners to the other dock, unless it also yields its corners.
+	CornersYield CornerPolicy = "yield"
+)
+
+// rank orders the docks on different edges from the inside to the outside of the layout, so the outer
+// docks span the edges of the inner docks. See orderDocks.
+func (c CornerPolicy) rank() int {
+	switch c {
+	case CornersYield:
+		return 0
+	case CornersOwn:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// orderDocks returns the order that docks are merged in, from the inside to the outside of the layout.
+// Docks on the same edge stay in the order they were added. The corner policies only decide the order
+// of docks on different edges, docks which own their corners are merged later so that they span the
+// edges of the other docks.
+func orderDocks(docks []layout) []layout {
+	// edges are the indexes of the remaining docks on each edge.
+	edges := make(map[Cardinal][]int)
+	for i, d := range docks {
+		edges[d.Cardinal] = append(edges[d.Cardinal], i)
+	}
+
+	ret := make([]layout, 0, len(docks))
+	for len(ret) < len(docks) {
+		// the next dock is the first remaining dock of an edge with the lowest rank, ties are broken
+		// by the order the docks were added.
+		next := -1
+		for _, remaining := range edges {
+			if len(remaining) == 0 {
+				continue
+			}
+			i := remaining[0]
+			if next < 0 || docks[i].Corners.rank() < docks[next].Corners.rank() ||
+				docks[i].Corners.rank() == docks[next].Corners.rank() && i < next {
+				next = i
+			}
+		}
+		ret = append(ret, docks[next])
+		c := docks[next].Cardinal
+		edges[c] = edges[c][1:]
+	}
+	return ret
+}
+
+// Dock defines a component that should span an entire side of the layout.
+type Dock struct {
+	// Cardinal indicates which side of the layout the view should be docked to.
+	Cardinal Cardinal
+
+	// Min overrides the minimum width or height that should be allocated for the view.
+	Min int
+
+	// Preferred overrides the Preferred width or height that should be allocated for the view.
+	Preferred int
+
+	// Max overrides the maximum width or height that should be allocated for the view.
+	Max int
+
+	// MinPercent, PreferredPercent and MaxPercent are sizes relative to the layout width or height.
+	MinPercent       int
+	PreferredPercent int
+	MaxPercent       int
+
+	// Grow allows the dock to be allocated extra space, GrowWeight and GrowPriority control how much.
+	// See BoundSize.Grow, BoundSize.GrowWeight and BoundSize.GrowPriority.
+	Grow         bool
+	GrowWeight   int
+	GrowPriority int
+
+	// Corners decides whether the dock spans the entire edge, or stops before the docks on the
+	// neighboring edges. See CornerPolicy.
+	Corners CornerPolicy
+
+	// Border draws a border around the view, reserving one row or column on each side.
+	Border BorderStyle
+
+	// Layer is the position of the view in the stacking order, views on a higher layer are on top.
+	// Defaults to 0, negative layers are below the default layer.
+	Layer int
+}
+
+// Pos places a view at a position relative to the layout instead of in the grid. Positioned views do not
+// take up any space in the grid, they are placed over it after the grid has been resized. This is useful
+// for modals, toasts and popups.
+type Pos struct {
+	// X and Y are the offset of the view from the position set by XPercent and YPercent.
+	X int
+	Y int
+
+	// XPercent and YPercent position the view within the layout: 0 is the left or top edge, 50 is centered and
+	// 100 is the right or bottom edge. For example a toast in the bottom right corner with a one cell gap is
+	// XPercent 100 and X -1.
+	XPercent int
+	YPercent int
+
+	// Width and Height are the size of the view.
+	Width  int
+	Height int
+
+	// WidthPercent and HeightPercent are sizes relative to the layout width or height, they are added to Width
+	// and Height. If the size is not set the view extends to the right or bottom edge of the layout.
+	WidthPercent  int
+	HeightPercent int
+
+	// Border draws a border around the view, reserving one row or column on each side.
+	Border BorderStyle
+
+	// Layer is the position of the view in the stacking order, views on a higher layer are on top.
+	// Defaults to 0, negative layers are below the default layer.
+	Layer int
+}
+
+// rect returns the area of the view in a layout of the given size. The view is kept inside the layout.
+func (p Pos) rect(width, height int) Rect {
+	x, w := posAxis(width, p.X, p.XPercent, p.Width, p.WidthPercent)
+	y, h := posAxis(height, p.Y, p.YPercent, p.Height, p.HeightPercent)
+	return Rect{X: x, Y: y, Width: w, Height: h}
+}
+
+// posAxis returns the start and length of a positioned view along one axis of the layout.
+func posAxis(size, offset, percent, length, lengthPercent int) (int, int) {
+	size = max(size, 0)
+	length += size * lengthPercent / 100
+	if length <= 0 {
+		start := min(max(size*percent/100+offset, 0), size)
+		return start, size - start
+	}
+	length = min(length, size)
+	start := min(max((size-length)*percent/100+offset, 0), size-length)
+	return start, length
+}
+
+type BubbleLayout interface {
+	MaybeAdd(string) (ID, error)
+	Add(string) ID
+	MaybeAddNamed(name, constraints string) (ID, error)
+	AddNamed(name, constraints string) ID
+	Cell(Cell) ID
+	Dock(Dock) ID
+	Pos(Pos) ID
+	Wrap()
+	SetVisible(id ID, visible bool)
+	Remove(id ID)
+	InsertAfter(id ID, constraints string) (ID, error)
+	Update(id ID, constraints string) error
+	Clear()
+	MaybeNest(child BubbleLayout, constraints string) (ID, error)
+	Nest(child BubbleLayout, constraints string) ID
+	Resize(width, height int) BubbleLayoutMsg
+	TryResize(width, height int) (BubbleLayoutMsg, error)
+	Validate() error
+}
+
+// NewWithConstraints creates a new BubbleLayout with the given size constraints.
+func NewWithConstraints(width, height PreferenceGroup) BubbleLayout {
+	// TODO: Verify these constraints.
+	return &bubbleLayout{
+		layouts: [][]layout{{}},
+		wUser:   width,
+		hUser:   height,
+	}
+}
+
+// New creates a new BubbleLayout. The optional constraints are the layout, column and row constraints,
+// E.g. New("insets 1", "[20!][grow][30:n:50]", "[3!][grow]"). The layout constraints use the string
+// notation and apply to the whole layout:
+//
+//	flowx:            views are added left to right, wrap starts a new row. This is the default.
+//	flowy:            views are added top to bottom, wrap starts a new column.
+//	ltr:              columns are placed left to right. This is the default.
+//	rtl:              columns are placed right to left.
+//	insets n [n n n]: space around the layout, using the same order as padding.
+//	gap x [y]:        the default gap between columns and rows, gapx and gapy set them individually.
+//
+// The column and row constraints have one entry in square brackets for each column or row, in the order
+// they are displayed including any docks. Entries contain a bound size and optionally grow, growprio,
+// shrink and shrinkprio. A number between two entries is the gap between them, it takes precedence over
+// the default gap. Numbers before the first entry or after the last one are the gaps at the edges.
+//
+// If there is an error New will panic, if you want to handle errors use MaybeNew.
+func New(constraints ...string) BubbleLayout {
+	bl, err := MaybeNew(constraints...)
+	if err != nil {
+		panic(err)
+	}
+	return bl
+}
+
+// MaybeNew is like New but returns an error if the constraints cannot be parsed.
+func MaybeNew(constraints ...string) (BubbleLayout, error) {
+	bl := &bubbleLayout{
+		layouts: [][]layout{{}},
+	}
+	if len(constraints) > 3 {
+		return nil, fmt.Errorf("too many layout constraints, expected at most 3 received %d", len(constraints))
+	}
+	if len(constraints) > 0 {
+		options, err := convertToLayoutOptions(constraints[0])
+		if err != nil {
+			return nil, err
+		}
+		bl.options = options
+	}
+	if len(constraints) > 1 {
+		wUser, colGaps, err := convertToAxisConstraints(constraints[1])
+		if err != nil {
+			return nil, err
+		}
+		bl.wUser, bl.options.colGaps = wUser, colGaps
+	}
+	if len(constraints) > 2 {
+		hUser, rowGaps, err := convertToAxisConstraints(constraints[2])
+		if err != nil {
+			return nil, err
+		}
+		bl.hUser, bl.options.rowGaps = hUser, rowGaps
+	}
+	return bl, nil
+}
+
+// layoutOptions are the constraints which apply to the whole layout.
+type layoutOptions struct {
+	// flowY indicates that views are added top to bottom instead of left to right.
+	flowY bool
+	// rightToLeft indicates that the columns are mirrored.
+	rightToLeft bool
+	// insets is the space around the layout.
+	insets Insets
+	// gapX and gapY are the default gaps between columns and rows.
+	gapX int
+	gapY int
+	// colGaps and rowGaps are the gaps from the column and row constraints, see gapsBefore.
+	colGaps []int
+	rowGaps []int
+}
+
+// defaultGap marks a gap which was not set in the column or row constraints.
+const defaultGap = -1
+
+// gapsBefore returns the space before each of the n entries of an axis, and the space after the last one.
+// The first entry includes the gap at the edge. Gaps between visible entries use the explicit gap when
+// there is one and the default gap otherwise. Collapsed entries do not have a gap.
+func gapsBefore(n int, explicit []int, def int, collapsed []bool) ([]int, int) {
+	gap := func(idx, def int) int {
+		if idx < len(explicit) && explicit[idx] != defaultGap {
+			return explicit[idx]
+		}
+		return def
+	}
+
+	before := make([]int, n)
+	visible := false
+	for idx := range before {
+		if idx == 0 {
+			before[idx] = gap(0, 0)
+		}
+		if idx < len(collapsed) && collapsed[idx] {
+			continue
+		}
+		if visible {
+			before[idx] = gap(idx, def)
+		}
+		visible = true
+	}
+	return before, gap(n, 0)
+}
+
+type bubbleLayout struct {
+	idCounter ID
+	layouts   Grid
+	docks     []layout
+	// positioned are the views which are placed with Pos, in the order they were added.
+	positioned []layout
+
+	// resizeCache is the layouts after being merged with the docks.
+	resizeCache Grid
+	hPref       PreferenceGroup
+	wPref       PreferenceGroup
+	// hCollapsed and wCollapsed are the rows and columns which only contain hidden views.
+	hCollapsed []bool
+	wCollapsed []bool
+
+	// hUser and wUser are the constraints provided by the user, they take precedence over the distilled preferences.
+	hUser PreferenceGroup
+	wUser PreferenceGroup
+
+	// options are the layout constraints.
+	options layoutOptions
+
+	// parent is set when this layout is nested in another one.
+	parent *bubbleLayout
+	// nested are the child layouts, by the ID of the cell they are placed in.
+	nested map[ID]*bubbleLayout
+}
+
+// MaybeAdd is like Add but returns an error if the string cannot be parsed into a valid Cell or Dock.
+func (bl *bubbleLayout) MaybeAdd(str string) (ID, error) {
+	l, err := parseView(str)
+	if err != nil {
+		return 0, err
+	}
+	return bl.addView(l), nil
+}
+
+// addView adds a parsed view to the grid, the docks or the positioned views.
+func (bl *bubbleLayout) addView(l layout) ID {
+	switch kindOf(l) {
+	case posView:
+		return bl.position(l)
+	case dockView:
+		return bl.dock(l)
+	default:
+		return bl.add(l)
+	}
+}
+
+// viewKind is the list that a view is stored in.
+type viewKind int
+
+const (
+	cellView viewKind = iota
+	dockView
+	posView
+)
+
+func (k viewKind) String() string {
+	switch k {
+	case dockView:
+		return "dock"
+	case posView:
+		return "positioned view"
+	default:
+		return "cell"
+	}
+}
+
+// kindOf returns whether a parsed view is a cell, a dock or a positioned view.
+func kindOf(l layout) viewKind {
+	switch {
+	case l.positioned:
+		return posView
+	case l.Cardinal != "":
+		return dockView
+	default:
+		return cellView
+	}
+}
+
+// parseView converts the string notation into a cell, dock or positioned view.
+func parseView(str string) (layout, error) {
+	l, err := convertToLayout(str)
+	if err != nil {
+		return layout{}, err
+	}
+	switch kindOf(l) {
+	case posView:
+		// cell options which also apply to positioned views.
+		l.Pos.Border, l.Pos.Layer = l.Cell.Border, l.Cell.Layer
+		l.Cell = Cell{}
+	case dockView:
+		// cell options which also apply to docks, docks only grow away from their edge.
+		l.Dock.Border, l.Dock.Layer = l.Cell.Border, l.Cell.Layer
+		grow := l.widthBound()
+		if l.Cardinal == NORTH || l.Cardinal == SOUTH {
+			grow = l.Cell.transpose().widthBound()
+		}
+		l.Dock.Grow, l.Dock.GrowWeight, l.Dock.GrowPriority = grow.Grow, grow.GrowWeight, grow.GrowPriority
+		l.Cell = Cell{}
+	}
+	return l, nil
+}
+
+// Add uses the string notation to define the layout. This is often shorter and easier to read than using the Layout struct.
+// If there is an error Add will panic. This is done for code readability, if you want to handle errors use MaybeAdd.
+func (bl *bubbleLayout) Add(str string) ID {
+	id, err := bl.MaybeAdd(str)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// MaybeAddNamed is like AddNamed but returns an error if the string cannot be parsed into a valid Cell or Dock.
+func (bl *bubbleLayout) MaybeAddNamed(name, str string) (ID, error) {
+	if name == "" {
+		return 0, fmt.Errorf("unable to add view: the name is empty")
+	}
+	l, err := parseView(str)
+	if err != nil {
+		return 0, err
+	}
+	if l.name != "" && l.name != name {
+		return 0, fmt.Errorf("unable to add view: it is named both '%s' and '%s'", name, l.name)
+	}
+	l.name = name
+	return bl.addView(l), nil
+}
+
+// AddNamed is like Add, except that the view is also given a name. This is the same as the "id" keyword. The
+// name can be used to find the view in the BubbleLayoutMsg, so the code does not depend on the order of
+// the views. Names must be unique, including the names in nested layouts.
+// If there is an error AddNamed will panic, if you want to handle errors use MaybeAddNamed.
+func (bl *bubbleLayout) AddNamed(name, str string) ID {
+	id, err := bl.MaybeAddNamed(name, str)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+func (bl *bubbleLayout) add(l layout) ID {
+	l.id = bl.nextID()
+	idx := len(bl.layouts) - 1
+	l.joinSplit = splitAvailable(bl.layouts[idx])
+	bl.layouts[idx] = append(bl.layouts[idx], l)
+
+	if l.wrap {
+		bl.layouts = append(bl.layouts, []layout{})
+	}
+	bl.invalidate()
+
+	// TODO: Debug mode which panics here as soon as a constraint violation is detected.
+	return l.id
+}
+
+// splitAvailable reports whether the last cell in the row is split and has room for another view.
+func splitAvailable(row []layout) bool {
+	members := 0
+	for i := len(row) - 1; i >= 0; i-- {
+		members++
+		if !row[i].joinSplit {
+			return row[i].Split > members
+		}
+	}
+	return false
+}
+
+// Cell adds a Cell to the Grid. By default, it is placed in the next available cell going left to right top to bottom.
+func (bl *bubbleLayout) Cell(c Cell) ID {
+	return bl.add(layout{Cell: c})
+}
+
+// Wrap inserts a new row into the layout, subsequent calls to Add will place models in the new row.
+func (bl *bubbleLayout) Wrap() {
+	bl.layouts = append(bl.layouts, []layout{})
+	bl.invalidate()
+}
+
+// Dock places a model on the edge of the layout, spanning the entire width or height.
+// For NORTH and SOUTH components, the width is fixed and the height is defined by Min, Preferred and Max.
+// For EAST and WEST components, the height is fixed and the width is defined by Min, Preferred and Max.
+func (bl *bubbleLayout) Dock(dock Dock) ID {
+	return bl.dock(layout{Dock: dock})
+}
+
+func (bl *bubbleLayout) dock(l layout) ID {
+	l.id = bl.nextID()
+	bl.docks = append(bl.docks, l)
+	bl.invalidate()
+	return l.id
+}
+
+// Pos places a model at a position relative to the layout, over the grid and docks. Positioned views are
+// placed in the order they were added, so the last one is on top.
+func (bl *bubbleLayout) Pos(pos Pos) ID {
+	return bl.position(layout{Pos: pos, positioned: true})
+}
+
+func (bl *bubbleLayout) position(l layout) ID {
+	l.id = bl.nextID()
+	bl.positioned = append(bl.positioned, l)
+	bl.invalidate()
+	return l.id
+}
+
+// nextID returns a new ID. Nested layouts use the IDs of their parent so that they are unique in the BubbleLayoutMsg.
+func (bl *bubbleLayout) nextID() ID {
+	if bl.parent != nil {
+		return bl.parent.nextID()
+	}
+	bl.idCounter++
+	return bl.idCounter
+}
+
+// MaybeNest is like Nest but returns an error if the constraints cannot be parsed or the layout cannot be nested.
+func (bl *bubbleLayout) MaybeNest(child BubbleLayout, str string) (ID, error) {
+	c, ok := child.(*bubbleLayout)
+	if !ok {
+		return 0, fmt.Errorf("unable to nest layout: unsupported BubbleLayout implementation")
+	}
+	if c == bl {
+		return 0, fmt.Errorf("unable to nest layout: a layout cannot be nested in itself")
+	}
+	if c.parent != nil {
+		return 0, fmt.Errorf("unable to nest layout: it is already nested")
+	}
+	if len(c.views()) > 0 || len(c.nested) > 0 {
+		return 0, fmt.Errorf("unable to nest layout: it must be empty")
+	}
+	l, err := parseView(str)
+	if err != nil {
+		return 0, err
+	}
+	var id ID
+	switch kindOf(l) {
+	case dockView:
+		return 0, fmt.Errorf("unable to nest layout: it cannot be docked")
+	case posView:
+		id = bl.position(l)
+	default:
+		id = bl.add(l)
+	}
+	c.parent = bl
+	if bl.nested == nil {
+		bl.nested = make(map[ID]*bubbleLayout)
+	}
+	bl.nested[id] = c
+	return id, nil
+}
+
+// unnest detaches a nested layout from its parent so that it can be used on its own or nested again. New views
+// continue from the IDs of the parent, so the IDs of views which were removed are not reused.
+func (bl *bubbleLayout) unnest() {
+	root := bl.parent
+	for root.parent != nil {
+		root = root.parent
+	}
+	bl.parent = nil
+	bl.idCounter = root.idCounter
+}
+
+// Nest places a child layout in a cell, the constraints use the same notation as Add. When the layout is
+// resized the child is resized to the content area of the cell and its views are included in the
+// BubbleLayoutMsg. The child must be empty when it is nested, views added to it afterwards are given
+// IDs which are unique across both layouts.
+// If there is an error Nest will panic, if you want to handle errors use MaybeNest.
+func (bl *bubbleLayout) Nest(child BubbleLayout, str string) ID {
+	id, err := bl.MaybeNest(child, str)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// find returns the layout for an ID, or nil if it is not part of the layout.
+func (bl *bubbleLayout) find(id ID) *layout {
+	loc, ok := bl.locate(id)
+	if !ok {
+		return nil
+	}
+	return loc.view()
+}
+
+// location is where a view is stored.
+type location struct {
+	// owner is the layout which contains the view, it is a nested layout if the view was added to one.
+	owner *bubbleLayout
+	kind  viewKind
+	// row is the row of the grid, it is only used for cells.
+	row   int
+	index int
+}
+
+// view returns the view at the location.
+func (loc location) view() *layout {
+	switch loc.kind {
+	case dockView:
+		return &loc.owner.docks[loc.index]
+	case posView:
+		return &loc.owner.positioned[loc.index]
+	default:
+		return &loc.owner.layouts[loc.row][loc.index]
+	}
+}
+
+// locate returns where a view is stored, or false if it is not part of the layout.
+func (bl *bubbleLayout) locate(id ID) (location, bool) {
+	for i := range bl.layouts {
+		for j := range bl.layouts[i] {
+			if bl.layouts[i][j].id == id {
+				return location{owner: bl, kind: cellView, row: i, index: j}, true
+			}
+		}
+	}
+	for i := range bl.docks {
+		if bl.docks[i].id == id {
+			return location{owner: bl, kind: dockView, index: i}, true
+		}
+	}
+	for i := range bl.positioned {
+		if bl.positioned[i].id == id {
+			return location{owner: bl, kind: posView, index: i}, true
+		}
+	}
+	for _, child := range bl.nested {
+		if loc, ok := child.locate(id); ok {
+			return loc, true
+		}
+	}
+	return location{}, false
+}
+
+// invalidate clears the resize cache so that the layout is recalculated.
+func (bl *bubbleLayout) invalidate() {
+	bl.resizeCache = nil
+	for _, child := range bl.nested {
+		child.invalidate()
+	}
+}
+
+// SetVisible shows or hides a view. Hidden views are allocated a zero size and
+// flagged as hidden in the BubbleLayoutMsg. Whether a hidden view still takes up
+// space is controlled by its HideMode, which is set with the "hidemode" keyword.
+func (bl *bubbleLayout) SetVisible(id ID, visible bool) {
+	l := bl.find(id)
+	if l == nil || l.hidden == !visible {
+		return
+	}
+	l.hidden = !visible
+	bl.invalidate()
+}
+
+// Remove removes a view from the layout. The IDs of the other views do not change and the ID is not reused.
+// If the view is part of a split cell the split has one less view, and if a nested layout is placed in the
+// view it is removed as well. Remove does nothing if the view is not part of the layout.
+func (bl *bubbleLayout) Remove(id ID) {
+	loc, ok := bl.locate(id)
+	if !ok {
+		return
+	}
+	owner := loc.owner
+	switch loc.kind {
+	case dockView:
+		owner.docks = removeLayout(owner.docks, loc.index)
+	case posView:
+		owner.positioned = removeLayout(owner.positioned, loc.index)
+	default:
+		owner.removeCell(loc.row, loc.index)
+	}
+	if child, ok := owner.nested[id]; ok {
+		child.unnest()
+		delete(owner.nested, id)
+	}
+	owner.invalidate()
+}
+
+// removeCell removes a cell from the grid. Rows which become empty are removed, except for the last row
+// which is where the next view is added.
+func (bl *bubbleLayout) removeCell(row, idx int) {
+	r := bl.layouts[row]
+	l := r[idx]
+	switch {
+	case l.joinSplit:
+		r[splitHead(r, idx)].Split--
+	case idx+1 < len(r) && r[idx+1].joinSplit:
+		// the next view becomes the first view of the split cell.
+		next := &r[idx+1]
+		next.joinSplit = false
+		next.Split = l.Split - 1
+		next.SpanWidth, next.SpanHeight = l.SpanWidth, l.SpanHeight
+	}
+	bl.layouts[row] = removeLayout(r, idx)
+	if len(bl.layouts[row]) == 0 && row < len(bl.layouts)-1 {
+		bl.layouts = append(bl.layouts[:row:row], bl.layouts[row+1:]...)
+	}
+}
+
+// InsertAfter adds a view directly after another view, the constraints use the same notation as Add. A cell
+// is inserted into the same row, and joins the split cell of the next view if there is one. If the new cell
+// wraps, the rest of the row is moved to a new row. Docks and positioned views can only be inserted after
+// a view of the same kind, they are placed as if they had been added after it.
+func (bl *bubbleLayout) InsertAfter(id ID, str string) (ID, error) {
+	loc, ok := bl.locate(id)
+	if !ok {
+		return 0, fmt.Errorf("unable to insert view: view not registered")
+	}
+	l, err := parseView(str)
+	if err != nil {
+		return 0, err
+	}
+	if kindOf(l) != loc.kind {
+		return 0, fmt.Errorf("unable to insert view: a %s cannot be inserted after a %s", kindOf(l), loc.kind)
+	}
+	owner := loc.owner
+	l.id = owner.nextID()
+	switch loc.kind {
+	case dockView:
+		owner.docks = insertLayout(owner.docks, loc.index+1, l)
+	case posView:
+		owner.positioned = insertLayout(owner.positioned, loc.index+1, l)
+	default:
+		owner.insertCell(loc.row, loc.index+1, l)
+	}
+	owner.invalidate()
+	return l.id, nil
+}
+
+// insertCell inserts a cell into a row of the grid.
+func (bl *bubbleLayout) insertCell(row, idx int, l layout) {
+	r := bl.layouts[row]
+	if idx < len(r) && r[idx].joinSplit {
+		l.joinSplit = true
+		r[splitHead(r, idx)].Split++
+	}
+	r = insertLayout(r, idx, l)
+	bl.layouts[row] = r
+	// the next row already starts after the last cell of the row, except for the last row.
+	if !l.wrap || (idx+1 == len(r) && row < len(bl.layouts)-1) {
+		return
+	}
+	rest := append([]layout{}, r[idx+1:]...)
+	bl.layouts[row] = r[:idx+1]
+	bl.layouts = append(bl.layouts[:row+1], append([][]layout{rest}, bl.layouts[row+1:]...)...)
+}
+
+// Update replaces the constraints of a view, the constraints use the same notation as Add. The view keeps its
+// ID and its place in the layout, including the split cell it is part of, so wrap and split have no effect.
+// The view keeps its name unless the constraints rename it, and a view hidden with SetVisible stays hidden. A
+// cell cannot become a dock or a positioned view, use Remove and InsertAfter instead.
+func (bl *bubbleLayout) Update(id ID, str string) error {
+	loc, ok := bl.locate(id)
+	if !ok {
+		return fmt.Errorf("unable to update view: view not registered")
+	}
+	l, err := parseView(str)
+	if err != nil {
+		return err
+	}
+	if kindOf(l) != loc.kind {
+		return fmt.Errorf("unable to update view: a %s cannot become a %s", loc.kind, kindOf(l))
+	}
+	prev := loc.view()
+	l.id, l.wrap, l.joinSplit, l.Split = prev.id, prev.wrap, prev.joinSplit, prev.Split
+	if l.name == "" {
+		l.name = prev.name
+	}
+	l.hidden = l.hidden || prev.hidden
+	*prev = l
+	loc.owner.invalidate()
+	return nil
+}
+
+// Clear removes every view from the layout, including the docks, positioned views and nested layouts. The
+// layout, column and row constraints are kept, and the IDs of the removed views are not reused.
+func (bl *bubbleLayout) Clear() {
+	bl.layouts = [][]layout{{}}
+	bl.docks = nil
+	bl.positioned = nil
+	for _, child := range bl.nested {
+		child.unnest()
+	}
+	bl.nested = nil
+	bl.invalidate()
+}
+
+// splitHead returns the index of the first view of the split cell which contains the view at idx.
+func splitHead(row []layout, idx int) int {
+	for idx > 0 && row[idx].joinSplit {
+		idx--
+	}
+	return idx
+}
+
+// removeLayout returns the layouts without the layout at idx, the original slice is not modified.
+func removeLayout(layouts []layout, idx int) []layout {
+	return append(layouts[:idx:idx], layouts[idx+1:]...)
+}
+
+// insertLayout returns a copy of the layouts with l inserted at idx.
+func insertLayout(layouts []layout, idx int, l layout) []layout {
+	ret := make([]layout, 0, len(layouts)+1)
+	ret = append(ret, layouts[:idx]...)
+	ret = append(ret, l)
+	return append(ret, layouts[idx:]...)
+}
+
+// ErrPreferenceConstraint is returned when the min, preferred and max sizes of a row or column contradict each other.
+type ErrPreferenceConstraint struct {
+	// Row is true when the constraint is for a row, otherwise it is for a column.
+	Row       bool
+	Index     int
+	Min       int
+	Preferred int
+	Max       int
+}
+
+func (p ErrPreferenceConstraint) Error() string {
+	var dir string
+	var dim string
+	if p.Row {
+		dir = "row"
+		dim = "height"
+	} else {
+		dir = "col"
+		dim = "width"
+	}
+	return fmt.Sprintf("constraint violation: %s %d: Min %s (%d), Preferred %s (%d) Max %s (%d)", dir, p.Index, dim, p.Min, dim, p.Preferred, dim, p.Max)
+}
+
+func makeRowViolation(idx, min, preferred, max int) error {
+	return ErrPreferenceConstraint{Row: true, Index: idx, Min: min, Preferred: preferred, Max: max}
+}
+
+func makeColViolation(idx, min, preferred, max int) error {
+	return ErrPreferenceConstraint{Row: false, Index: idx, Min: min, Preferred: preferred, Max: max}
+}
+
+// ErrPreferenceCount is returned when the number of user provided preferences does not match the grid.
+type ErrPreferenceCount struct {
+	Axis     Axis
+	Expected int
+	Received int
+}
+
+func (e ErrPreferenceCount) Error() string {
+	if e.Axis == Horizontal {
+		return fmt.Sprintf("width preferences do not match the number of columns: expected %d, received %d", e.Expected, e.Received)
+	}
+	return fmt.Sprintf("height preferences do not match the number of rows: expected %d, received %d", e.Expected, e.Received)
+}
+
+// ErrSpan is returned when a view has an invalid span, or when its span covers a cell which is already covered by
+// the vertical span of another view.
+type ErrSpan struct {
+	ID         ID
+	SpanWidth  int
+	SpanHeight int
+	// Overlap is the view whose span was overlapped, it is zero when the span is negative.
+	Overlap ID
+}
+
+func (e ErrSpan) Error() string {
+	if e.SpanWidth >= 0 && e.SpanHeight >= 0 {
+		return fmt.Sprintf("invalid span for view %d: span %d %d overlaps the span of view %d", e.ID, e.SpanWidth, e.SpanHeight, e.Overlap)
+	}
+	return fmt.Sprintf("invalid span for view %d: spans must not be negative, received %d %d", e.ID, e.SpanWidth, e.SpanHeight)
+}
+
+// ErrValue is returned when a value of a view is out of range, for example a negative padding.
+type ErrValue struct {
+	ID    ID
+	Field string
+	Value int
+}
+
+func (e ErrValue) Error() string {
+	return fmt.Sprintf("invalid %s for view %d: it must not be negative, received %d", e.Field, e.ID, e.Value)
+}
+
+// ErrOption is returned when a view has an unknown border style or alignment.
+type ErrOption struct {
+	ID    ID
+	Field string
+	Value string
+}
+
+func (e ErrOption) Error() string {
+	return fmt.Sprintf("invalid %s for view %d: '%s'", e.Field, e.ID, e.Value)
+}
+
+// ErrDock is returned when a docked view has an invalid cardinal direction or corner policy.
+type ErrDock struct {
+	ID       ID
+	Cardinal Cardinal
+	Corners  CornerPolicy
+}
+
+func (e ErrDock) Error() string {
+	if !isCardinal(string(e.Cardinal)) {
+		return fmt.Sprintf("invalid cardinal direction for docked view %d: '%s'", e.ID, e.Cardinal)
+	}
+	return fmt.Sprintf("invalid corner policy for docked view %d: '%s'", e.ID, e.Corners)
+}
+
+// ErrDuplicateName is returned when more than one view has the same name.
+type ErrDuplicateName struct {
+	Name   string
+	First  ID
+	Second ID
+}
+
+func (e ErrDuplicateName) Error() string {
+	return fmt.Sprintf("duplicate name '%s' for views %d and %d: names must be unique", e.Name, e.First, e.Second)
+}
+
+// checkLayouts checks the cells, docks and positioned views for problems which would prevent them from being placed.
+func checkLayouts(layouts Grid, docks, positioned []layout) error {
+	for _, row := range layouts {
+		for _, l := range row {
+			if l.SpanWidth < 0 || l.SpanHeight < 0 {
+				return ErrSpan{ID: l.id, SpanWidth: l.SpanWidth, SpanHeight: l.SpanHeight}
+			}
+			if err := checkCellValues(l); err != nil {
+				return err
+			}
+			if err := checkOptions(l.id, l.Cell.Border, l.AlignX, l.AlignY); err != nil {
+				return err
+			}
+		}
+	}
+	for _, d := range docks {
+		if !isCardinal(string(d.Cardinal)) || !isCornerPolicy(string(d.Corners)) {
+			return ErrDock{ID: d.id, Cardinal: d.Cardinal, Corners: d.Corners}
+		}
+		if err := checkDockValues(d); err != nil {
+			return err
+		}
+		if err := checkOptions(d.id, d.Dock.Border, "", ""); err != nil {
+			return err
+		}
+	}
+	for _, p := range positioned {
+		if err := checkOptions(p.id, p.Pos.Border, "", ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkOptions returns an ErrOption for an unknown border style or alignment, they are optional.
+func checkOptions(id ID, border BorderStyle, alignX, alignY Alignment) error {
+	switch {
+	case border != BorderNone && !isBorderStyle(string(border)):
+		return ErrOption{ID: id, Field: "Border", Value: string(border)}
+	case alignX != "" && !isAlignX(string(alignX)):
+		return ErrOption{ID: id, Field: "AlignX", Value: string(alignX)}
+	case alignY != "" && !isAlignY(string(alignY)):
+		return ErrOption{ID: id, Field: "AlignY", Value: string(alignY)}
+	}
+	return nil
+}
+
+// namedValue is a value of a view, the name is used in an ErrValue.
+type namedValue struct {
+	name  string
+	value int
+}
+
+// checkValues returns an ErrValue for the first negative value.
+func checkValues(id ID, values []namedValue) error {
+	for _, v := range values {
+		if v.value < 0 {
+			return ErrValue{ID: id, Field: v.name, Value: v.value}
+		}
+	}
+	return nil
+}
+
+// checkCellValues checks the values of a cell which cannot be negative. The shrink weights can also be NoShrink.
+func checkCellValues(l layout) error {
+	shrinkWeight := func(w int) int {
+		if w == NoShrink {
+			return 0
+		}
+		return w
+	}
+	return checkValues(l.id, []namedValue{
+		{"MinWidth", l.MinWidth}, {"PreferredWidth", l.PreferredWidth}, {"MaxWidth", l.MaxWidth},
+		{"MinHeight", l.MinHeight}, {"PreferredHeight", l.PreferredHeight}, {"MaxHeight", l.MaxHeight},
+		{"MinWidthPercent", l.MinWidthPercent}, {"PreferredWidthPercent", l.PreferredWidthPercent},
+		{"MaxWidthPercent", l.MaxWidthPercent}, {"MinHeightPercent", l.MinHeightPercent},
+		{"PreferredHeightPercent", l.PreferredHeightPercent}, {"MaxHeightPercent", l.MaxHeightPercent},
+		{"GrowWidthWeight", l.GrowWidthWeight}, {"GrowHeightWeight", l.GrowHeightWeight},
+		{"ShrinkWidthWeight", shrinkWeight(l.ShrinkWidthWeight)}, {"ShrinkHeightWeight", shrinkWeight(l.ShrinkHeightWeight)},
+		{"Padding.Top", l.Padding.Top}, {"Padding.Left", l.Padding.Left},
+		{"Padding.Bottom", l.Padding.Bottom}, {"Padding.Right", l.Padding.Right},
+		{"Margin.Top", l.Margin.Top}, {"Margin.Left", l.Margin.Left},
+		{"Margin.Bottom", l.Margin.Bottom}, {"Margin.Right", l.Margin.Right},
+	})
+}
+
+// checkDockValues checks the values of a dock which cannot be negative.
+func checkDockValues(d layout) error {
+	return checkValues(d.id, []namedValue{
+		{"Min", d.Dock.Min}, {"Preferred", d.Dock.Preferred}, {"Max", d.Dock.Max},
+		{"MinPercent", d.MinPercent}, {"PreferredPercent", d.PreferredPercent}, {"MaxPercent", d.MaxPercent},
+		{"GrowWeight", d.GrowWeight},
+	})
+}
+
+func checkPreferenceConstraints(hPref, wPref PreferenceGroup) error {
+	hasConstraintViolation := func(b BoundSize) bool {
+		if b.Max != 0 {
+			return b.Min > b.Max || b.Preferred > b.Max
+		}
+		if b.Preferred != 0 {
+			return b.Min > b.Preferred
+		}
+		return false
+	}
+	for row, p := range hPref {
+		if hasConstraintViolation(p) {
+			return makeRowViolation(row, p.Min, p.Preferred, p.Max)
+		}
+	}
 
-	// Preferred overrides the Preferred width or height that should be allocated for the view.
-	Preferred int
+	for col, p := range wPref {
+		if hasConstraintViolation(p) {
+			return makeColViolation(col, p.Min, p.Preferred, p.Max)
+		}
+	}
 
-	// Max overrides the maximum width or height that should be allocated for the view.
-	Max int
+	return nil
 }
 
-type BubbleLayout interface {
-	MaybeAdd(string) (ID, error)
-	Add(string) ID
-	Cell(Cell) ID
-	Dock(Dock) ID
-	Wrap()
-	Resize(width, height int) BubbleLayoutMsg
-	Validate() error
+// reserveInsets takes a layout and grows the size preferences of each cell to
+// make room for its border, padding and margin. The border and padding are part
+// of the view so they become the minimum size, the margin is outside of the view
+// so it is added to every preference.
+func reserveInsets(layouts Grid) Grid {
+	ret := make(Grid, len(layouts))
+	for i, row := range layouts {
+		ret[i] = make([]layout, len(row))
+		for j, l := range row {
+			if l.Padding != (Insets{}) || l.Margin != (Insets{}) || l.Cell.Border != BorderNone {
+				border := 2 * l.Cell.Border.size()
+				l.MinWidth, l.PreferredWidth, l.MaxWidth = reserve(l.MinWidth, l.PreferredWidth, l.MaxWidth, l.Padding.horizontal()+border, l.Margin.horizontal())
+				l.MinHeight, l.PreferredHeight, l.MaxHeight = reserve(l.MinHeight, l.PreferredHeight, l.MaxHeight, l.Padding.vertical()+border, l.Margin.vertical())
+			}
+			ret[i][j] = l
+		}
+	}
+	return ret
 }
 
-// NewWithConstraints creates a new BubbleLayout with the given size constraints.
-func NewWithConstraints(width, height PreferenceGroup) BubbleLayout {
-	// TODO: Verify these constraints.
-	return &bubbleLayout{
-		layouts: [][]layout{{}},
-		wPref:   width,
-		hPref:   height,
+// reserve adjusts a min/preferred/max combination for the padding and margin. The sizes include the padding,
+// so they are raised to at least the padding. Unset preferences remain unset.
+func reserve(minimum, preferred, maximum, padding, margin int) (int, int, int) {
+	minimum = max(minimum, padding)
+	if preferred != 0 {
+		preferred = max(preferred, minimum) + margin
+	}
+	if maximum != 0 {
+		maximum = max(maximum, minimum) + margin
 	}
+	return minimum + margin, preferred, maximum
 }
 
-func New() BubbleLayout {
-	return &bubbleLayout{
-		layouts: [][]layout{{}},
-	}
+// transpose swaps the horizontal and vertical preferences of the cell.
+func (c Cell) transpose() Cell {
+	c.SpanWidth, c.SpanHeight = c.SpanHeight, c.SpanWidth
+	c.MinWidth, c.MinHeight = c.MinHeight, c.MinWidth
+	c.PreferredWidth, c.PreferredHeight = c.PreferredHeight, c.PreferredWidth
+	c.MaxWidth, c.MaxHeight = c.MaxHeight, c.MaxWidth
+	c.MinWidthPercent, c.MinHeightPercent = c.MinHeightPercent, c.MinWidthPercent
+	c.PreferredWidthPercent, c.PreferredHeightPercent = c.PreferredHeightPercent, c.PreferredWidthPercent
+	c.MaxWidthPercent, c.MaxHeightPercent = c.MaxHeightPercent, c.MaxWidthPercent
+	c.GrowWidth, c.GrowHeight = c.GrowHeight, c.GrowWidth
+	c.GrowWidthWeight, c.GrowHeightWeight = c.GrowHeightWeight, c.GrowWidthWeight
+	c.GrowWidthPriority, c.GrowHeightPriority = c.GrowHeightPriority, c.GrowWidthPriority
+	c.ShrinkWidthWeight, c.ShrinkHeightWeight = c.ShrinkHeightWeight, c.ShrinkWidthWeight
+	c.ShrinkWidthPriority, c.ShrinkHeightPriority = c.ShrinkHeightPriority, c.ShrinkWidthPriority
+	c.SizeGroupWidth, c.SizeGroupHeight = c.SizeGroupHeight, c.SizeGroupWidth
+	c.Padding = c.Padding.transpose()
+	c.Margin = c.Margin.transpose()
+	c.wDuplicate, c.hDuplicate = c.hDuplicate, c.wDuplicate
+	return c
 }
 
-type bubbleLayout struct {
-	idCounter ID
-	layouts   Grid
-	docks     []layout
+// transpose swaps the cell and the views in a split cell, which changes the direction of the split.
+func (l layout) transpose() layout {
+	l.Cell = l.Cell.transpose()
+	if len(l.split) > 0 {
+		split := make([]layout, len(l.split))
+		for i, m := range l.split {
+			split[i] = m.transpose()
+		}
+		l.split = split
+		l.splitVertical = !l.splitVertical
+	}
+	return l
+}
 
-	// resizeCache is the layouts after being merged with the docks.
-	resizeCache Grid
-	hPref       PreferenceGroup
-	wPref       PreferenceGroup
+// transposeCells returns a copy of the layouts where every cell is transposed, the shape of the grid is unchanged.
+// This is used to build a column major layout as if it were row major.
+func transposeCells(layouts Grid) Grid {
+	ret := make(Grid, len(layouts))
+	for i, row := range layouts {
+		ret[i] = make([]layout, len(row))
+		for j, l := range row {
+			ret[i][j] = l.transpose()
+		}
+	}
+	return ret
 }
 
-// MaybeAdd is like Add but returns an error if the string cannot be parsed into a valid Cell or Dock.
-func (bl *bubbleLayout) MaybeAdd(str string) (ID, error) {
-	l, err := convertToLayout(str)
-	if err != nil {
-		return 0, err
+// transposeGrid swaps the rows and columns of the grid, and transposes every cell. The grid must be rectangular.
+func transposeGrid(g Grid) Grid {
+	if len(g) == 0 {
+		return nil
 	}
-	if l.Dock == (Dock{}) {
-		return bl.add(l), nil
-	} else {
-		return bl.Dock(l.Dock), nil
+	ret := make(Grid, len(g[0]))
+	for col := range ret {
+		ret[col] = make([]layout, len(g))
+		for row := range g {
+			ret[col][row] = g[row][col].transpose()
+		}
 	}
+	return ret
 }
 
-// Add uses the string notation to define the layout. This is often shorter and easier to read than using the Layout struct.
-// If there is an error Add will panic. This is done for code readability, if you want to handle errors use MaybeAdd.
-func (bl *bubbleLayout) Add(str string) ID {
-	id, err := bl.MaybeAdd(str)
-	if err != nil {
-		panic(err)
+// mirrorGrid returns a copy of the grid with the columns in reverse order, for right to left layouts.
+// The views in split cells are reversed as well.
+func mirrorGrid(g Grid) Grid {
+	ret := make(Grid, len(g))
+	for i, row := range g {
+		ret[i] = make([]layout, len(row))
+		for j, l := range row {
+			if len(l.split) > 0 && !l.splitVertical {
+				split := make([]layout, len(l.split))
+				for k, m := range l.split {
+					split[len(split)-1-k] = m
+				}
+				l.split = split
+			}
+			l.Padding.Left, l.Padding.Right = l.Padding.Right, l.Padding.Left
+			l.Margin.Left, l.Margin.Right = l.Margin.Right, l.Margin.Left
+			ret[i][len(row)-1-j] = l
+		}
+		// the first column of a span is no longer on the left.
+		for j := range ret[i] {
+			ret[i][j].wDuplicate = j > 0 && ret[i][j].id != 0 && ret[i][j-1].id == ret[i][j].id
+		}
 	}
-	return id
+	return ret
 }
 
-func (bl *bubbleLayout) add(l layout) ID {
-	bl.idCounter++
-	l.id = bl.idCounter
-	idx := len(bl.layouts) - 1
-	bl.layouts[idx] = append(bl.layouts[idx], l)
-
-	if l.wrap {
-		bl.layouts = append(bl.layouts, []layout{})
+// mergeSplits returns a copy of the layouts where the views of each split cell are merged
+// into a single cell. The merged cell has the ID of the first view, the size preferences of
+// all views combined and the views themselves are kept in split.
+func mergeSplits(layouts Grid) Grid {
+	ret := make(Grid, len(layouts))
+	for i, row := range layouts {
+		ret[i] = make([]layout, 0, len(row))
+		for _, l := range row {
+			last := len(ret[i]) - 1
+			if !l.joinSplit || last < 0 {
+				ret[i] = append(ret[i], l)
+				continue
+			}
+			host := &ret[i][last]
+			if len(host.split) == 0 {
+				host.split = []layout{*host}
+			}
+			host.split = append(host.split, l)
+			host.Cell = splitCell(host.split)
+			host.hidden, host.hideMode = splitHidden(host.split)
+		}
 	}
-
-	// TODO: Debug mode which panics here as soon as a constraint violation is detected.
-	return bl.idCounter
+	return ret
 }
 
-// Cell adds a Cell to the Grid. By default, it is placed in the next available cell going left to right top to bottom.
-func (bl *bubbleLayout) Cell(c Cell) ID {
-	return bl.add(layout{Cell: c})
+// splitCell combines the size preferences of the views in a split cell. The views are
+// next to each other, so the widths are added together and the tallest height is used.
+// Width percentages are relative to the split cell, so they are not included.
+func splitCell(members []layout) Cell {
+	c := Cell{SpanWidth: members[0].SpanWidth, SpanHeight: members[0].SpanHeight, Split: members[0].Split}
+	allPreferred, allMaxWidth, allMaxHeight := true, true, true
+	for _, m := range members {
+		if m.collapsed() {
+			continue
+		}
+		c.MinWidth += m.MinWidth
+		c.PreferredWidth += max(m.PreferredWidth, m.MinWidth)
+		c.MaxWidth += m.MaxWidth
+		allPreferred = allPreferred && m.PreferredWidth != 0
+		allMaxWidth = allMaxWidth && m.MaxWidth != 0
+		c.GrowWidth = c.GrowWidth || m.GrowWidth
+		c.GrowWidthWeight = max(c.GrowWidthWeight, m.GrowWidthWeight)
+		c.GrowWidthPriority = max(c.GrowWidthPriority, m.GrowWidthPriority)
+		c.ShrinkWidthWeight = minNonZero(c.ShrinkWidthWeight, m.ShrinkWidthWeight)
+		c.ShrinkWidthPriority = minNonZero(c.ShrinkWidthPriority, m.ShrinkWidthPriority)
+
+		c.MinHeight = max(c.MinHeight, m.MinHeight)
+		c.PreferredHeight = max(c.PreferredHeight, m.PreferredHeight)
+		c.MaxHeight = max(c.MaxHeight, m.MaxHeight)
+		allMaxHeight = allMaxHeight && m.MaxHeight != 0
+		c.MinHeightPercent = max(c.MinHeightPercent, m.MinHeightPercent)
+		c.PreferredHeightPercent = max(c.PreferredHeightPercent, m.PreferredHeightPercent)
+		c.GrowHeight = c.GrowHeight || m.GrowHeight
+		c.GrowHeightWeight = max(c.GrowHeightWeight, m.GrowHeightWeight)
+		c.GrowHeightPriority = max(c.GrowHeightPriority, m.GrowHeightPriority)
+		c.ShrinkHeightWeight = minNonZero(c.ShrinkHeightWeight, m.ShrinkHeightWeight)
+		c.ShrinkHeightPriority = minNonZero(c.ShrinkHeightPriority, m.ShrinkHeightPriority)
+	}
+	// a view without a preference grows, so the split cell cannot have a fixed preferred width.
+	if !allPreferred {
+		c.PreferredWidth = 0
+	}
+	// a view without a max could use any amount of space.
+	if !allMaxWidth {
+		c.MaxWidth = 0
+	}
+	if !allMaxHeight {
+		c.MaxHeight = 0
+	}
+	return c
 }
 
-// Wrap inserts a new row into the layout, subsequent calls to Add will place models in the new row.
-func (bl *bubbleLayout) Wrap() {
-	bl.layouts = append(bl.layouts, []layout{})
+// splitHidden returns the visibility of a split cell, it is only hidden when all of its views are.
+func splitHidden(members []layout) (bool, HideMode) {
+	mode := HideModeCollapse
+	for _, m := range members {
+		if !m.hidden {
+			return false, HideModeReserve
+		}
+		if m.hideMode != HideModeCollapse {
+			mode = HideModeReserve
+		}
+	}
+	return true, mode
 }
 
-// Dock places a model on the edge of the layout, spanning the entire width or height.
-// For NORTH and SOUTH components, the width is fixed and the height is defined by Min, Preferred and Max.
-// For EAST and WEST components, the height is fixed and the width is defined by Min, Preferred and Max.
-func (bl *bubbleLayout) Dock(dock Dock) ID {
-	bl.idCounter++
-	bl.docks = append(bl.docks, layout{id: bl.idCounter, Dock: dock})
-	return bl.idCounter
+// widthBound returns the width preferences of the cell.
+func (c Cell) widthBound() BoundSize {
+	return BoundSize{
+		Min:              c.MinWidth,
+		Preferred:        c.PreferredWidth,
+		Max:              c.MaxWidth,
+		Grow:             c.GrowWidth,
+		MinPercent:       c.MinWidthPercent,
+		PreferredPercent: c.PreferredWidthPercent,
+		MaxPercent:       c.MaxWidthPercent,
+		GrowWeight:       c.GrowWidthWeight,
+		GrowPriority:     c.GrowWidthPriority,
+		ShrinkWeight:     c.ShrinkWidthWeight,
+		ShrinkPriority:   c.ShrinkWidthPriority,
+	}
 }
 
-type preferenceConstraintError struct {
-	row                 bool
-	idx, min, pref, max int
+// splitBounds returns the preferences of the views in a split cell, in the direction of the split.
+func splitBounds(members []layout, vertical bool) (PreferenceGroup, []bool) {
+	pg := make(PreferenceGroup, len(members))
+	collapsed := make([]bool, len(members))
+	for i, m := range members {
+		if vertical {
+			pg[i] = m.Cell.transpose().widthBound()
+		} else {
+			pg[i] = m.widthBound()
+		}
+		collapsed[i] = m.collapsed()
+	}
+	return pg, collapsed
 }
 
-func (p preferenceConstraintError) Error() string {
-	var dir string
-	var dim string
-	if p.row {
-		dir = "row"
-		dim = "width"
-	} else {
-		dir = "col"
-		dim = "height"
+// hideLayouts returns a copy of the layouts where collapsed views no longer have any size preferences.
+func hideLayouts(layouts Grid) Grid {
+	ret := make(Grid, len(layouts))
+	for i, row := range layouts {
+		ret[i] = hideDocks(row)
 	}
-	return fmt.Sprintf("constraint violation: %s %d: Min %s (%d), Preferred %s (%d) Max %s (%d)", dir, p.idx, dim, p.min, dim, p.pref, dim, p.max)
+	return ret
 }
 
-func makeRowViolation(idx, min, preferred, max int) error {
-	return preferenceConstraintError{row: true, idx: idx, min: min, pref: preferred, max: max}
+// widthGroup returns the name of the size group for the width, or an empty string.
+func (c Cell) widthGroup() string {
+	if c.SizeGroupWidth != "" {
+		return c.SizeGroupWidth
+	}
+	return c.SizeGroup
 }
 
-func makeColViolation(idx, min, preferred, max int) error {
-	return preferenceConstraintError{row: false, idx: idx, min: min, pref: preferred, max: max}
+// heightGroup returns the name of the size group for the height, or an empty string.
+func (c Cell) heightGroup() string {
+	if c.SizeGroupHeight != "" {
+		return c.SizeGroupHeight
+	}
+	return c.SizeGroup
 }
 
-func checkPreferenceConstraints(hPref, wPref PreferenceGroup) error {
-	hasConstraintViolation := func(b BoundSize) bool {
-		if b.Max != 0 {
-			return b.Min > b.Max || b.Preferred > b.Max
+// reconcileSizeGroups returns a copy of the layouts where the views in a size group share the same preferences.
+// The group uses the largest min and preferred size of its views, and the largest max unless one of them is
+// unlimited. Collapsed views are not part of their group.
+func reconcileSizeGroups(layouts Grid) Grid {
+	widths := make(map[string]BoundSize)
+	heights := make(map[string]BoundSize)
+	combine := func(groups map[string]BoundSize, name string, b BoundSize) {
+		g, ok := groups[name]
+		if !ok {
+			groups[name] = b
+			return
 		}
-		if b.Preferred != 0 {
-			return b.Min > b.Preferred
+		g.Min, g.MinPercent = max(g.Min, b.Min), max(g.MinPercent, b.MinPercent)
+		g.Preferred, g.PreferredPercent = max(g.Preferred, b.Preferred), max(g.PreferredPercent, b.PreferredPercent)
+		if g.Max == 0 && g.MaxPercent == 0 || b.Max == 0 && b.MaxPercent == 0 {
+			g.Max, g.MaxPercent = 0, 0
+		} else {
+			g.Max, g.MaxPercent = max(g.Max, b.Max), max(g.MaxPercent, b.MaxPercent)
 		}
-		return false
+		groups[name] = g
 	}
-	for row, p := range hPref {
-		if hasConstraintViolation(p) {
-			return makeRowViolation(row, p.Min, p.Preferred, p.Max)
+	for _, row := range layouts {
+		for _, l := range row {
+			if l.collapsed() {
+				continue
+			}
+			if name := l.widthGroup(); name != "" {
+				combine(widths, name, l.widthBound())
+			}
+			if name := l.heightGroup(); name != "" {
+				combine(heights, name, l.Cell.transpose().widthBound())
+			}
 		}
 	}
 
-	for col, p := range wPref {
-		if hasConstraintViolation(p) {
-			return makeColViolation(col, p.Min, p.Preferred, p.Max)
+	ret := make(Grid, len(layouts))
+	for i, row := range layouts {
+		ret[i] = make([]layout, len(row))
+		for j, l := range row {
+			if g, ok := widths[l.widthGroup()]; ok && !l.collapsed() {
+				l.MinWidth, l.PreferredWidth, l.MaxWidth = g.Min, g.Preferred, g.Max
+				l.MinWidthPercent, l.PreferredWidthPercent, l.MaxWidthPercent = g.MinPercent, g.PreferredPercent, g.MaxPercent
+			}
+			if g, ok := heights[l.heightGroup()]; ok && !l.collapsed() {
+				l.MinHeight, l.PreferredHeight, l.MaxHeight = g.Min, g.Preferred, g.Max
+				l.MinHeightPercent, l.PreferredHeightPercent, l.MaxHeightPercent = g.MinPercent, g.PreferredPercent, g.MaxPercent
+			}
+			ret[i][j] = l
 		}
 	}
+	return ret
+}
 
-	return nil
+// releaseAligned returns a copy of the layouts where the maximum size of aligned views no longer limits their
+// row or column. The views are aligned within the extra space instead.
+func releaseAligned(layouts Grid) Grid {
+	ret := make(Grid, len(layouts))
+	for i, row := range layouts {
+		ret[i] = make([]layout, len(row))
+		for j, l := range row {
+			if l.AlignX != "" {
+				l.MaxWidth, l.MaxWidthPercent = 0, 0
+			}
+			if l.AlignY != "" {
+				l.MaxHeight, l.MaxHeightPercent = 0, 0
+			}
+			ret[i][j] = l
+		}
+	}
+	return ret
+}
+
+// hideDocks returns a copy of the docks where collapsed views no longer have any size preferences.
+func hideDocks(docks []layout) []layout {
+	ret := make([]layout, len(docks))
+	for i, l := range docks {
+		if l.collapsed() {
+			// spans are needed to keep the shape of the grid.
+			l.Cell = Cell{SpanWidth: l.SpanWidth, SpanHeight: l.SpanHeight, Split: l.Split}
+			l.Dock = Dock{Cardinal: l.Cardinal, Corners: l.Corners}
+		}
+		ret[i] = l
+	}
+	return ret
+}
+
+// collapsedRowsAndCols finds the rows and columns which contain collapsed views
+// and nothing else that is visible. They should not be allocated any space.
+func collapsedRowsAndCols(g Grid) (rows, cols []bool) {
+	if len(g) == 0 {
+		return nil, nil
+	}
+	rows = make([]bool, len(g))
+	cols = make([]bool, len(g[0]))
+	rowVisible := make([]bool, len(g))
+	colVisible := make([]bool, len(g[0]))
+	for rowIdx, row := range g {
+		for colIdx, l := range row {
+			switch {
+			case l.collapsed():
+				rows[rowIdx] = true
+				cols[colIdx] = true
+			case l.id != 0:
+				rowVisible[rowIdx] = true
+				colVisible[colIdx] = true
+			}
+		}
+	}
+	for i := range rows {
+		rows[i] = rows[i] && !rowVisible[i]
+	}
+	for i := range cols {
+		cols[i] = cols[i] && !colVisible[i]
+	}
+	return rows, cols
 }
 
 // expandSpans takes a layout and splits all spans into individual cells. This is a simplification, because
@@ -483,90 +2478,79 @@ func checkPreferenceConstraints(hPref, wPref PreferenceGroup) error {
 // |   -   |   6   |       7       |
 // ---------------------------------
 //
-// In the above example, the 2x2 cell is split into 4 cells, and the 1x2 cells are split into 2 cells.
-func expandSpans(layouts Grid) Grid {
-	ret := make(Grid, len(layouts))
-	for i := 0; i < len(layouts); i++ {
-		ret[i] = make([]layout, 0, len(layouts[i]))
-		ret[i] = append(ret[i], layouts[i]...)
+// In the above example, the 2x2 cell is split into 4 cells, and the 1x2 cells are split into 2 cells. Cells are
+// placed in the first column of their row which is not covered by a vertical span from a row above, and the rows
+// are padded with empty cells so that every row has the same length. An ErrSpan is returned if a horizontal span
+// would cover a cell which is already covered by a vertical span.
+func expandSpans(layouts Grid) (Grid, error) {
+	var ret Grid
+	var filled [][]bool
+	// grow adds rows and columns until the cell exists.
+	grow := func(rowIdx, colIdx int) {
+		for len(ret) <= rowIdx {
+			ret = append(ret, nil)
+			filled = append(filled, nil)
+		}
+		for len(ret[rowIdx]) <= colIdx {
+			ret[rowIdx] = append(ret[rowIdx], layout{})
+			filled[rowIdx] = append(filled[rowIdx], false)
+		}
 	}
 
-	longestCol := 0
-	for _, row := range ret {
-		longestCol = max(longestCol, len(row))
-		curRow := 0
+	for rowIdx, row := range layouts {
+		grow(rowIdx, -1)
+		colIdx := 0
 		for _, l := range row {
-			if l.SpanWidth > 0 {
-				curRow += l.SpanWidth
-			} else {
-				curRow++
+			// skip the cells covered by vertical spans from the rows above.
+			for colIdx < len(filled[rowIdx]) && filled[rowIdx][colIdx] {
+				colIdx++
 			}
-		}
-	}
 
-	// spanx and create rows
-	for colIdx := 0; colIdx < longestCol; colIdx++ {
-		var rowIdx int
-		for rowIdx = 0; rowIdx < len(ret); rowIdx++ {
-			// pad empty cells
-			if len(ret[rowIdx]) < (colIdx + 1) {
-				ret[rowIdx] = append(ret[rowIdx], layout{})
+			spanWidth, spanHeight := max(1, l.SpanWidth), max(1, l.SpanHeight)
+			span := l
+			// TODO: fix rounding errors?
+			if spanWidth > 1 {
+				span.MinWidth /= spanWidth
+				span.MaxWidth /= spanWidth
+				span.PreferredWidth /= spanWidth
+				span.MinWidthPercent /= spanWidth
+				span.MaxWidthPercent /= spanWidth
+				span.PreferredWidthPercent /= spanWidth
 			}
-
-			// vertical span duplicate handling.
-			spanHeight := ret[rowIdx][colIdx].SpanHeight
-			if ret[rowIdx][colIdx].SpanHeight > 1 && !ret[rowIdx][colIdx].hDuplicate {
-				// TODO: fix rounding errors?
-				l := ret[rowIdx][colIdx]
-				l.MinHeight /= l.SpanHeight
-				l.MaxHeight /= l.SpanHeight
-				l.PreferredHeight /= l.SpanHeight
-
-				if ret[rowIdx][colIdx].SpanWidth > 1 && ret[rowIdx][colIdx].wDuplicate {
-					// already handled by the horizontal span duplicate handling.
-				} else {
-					ret[rowIdx][colIdx] = l
-					l.hDuplicate = true
-					for i := 1; i < spanHeight; i++ {
-						// create next row if needed
-						if len(ret) <= (rowIdx + i) {
-							// pad next row to colIdx so that we can append the new cell.
-							ret = append(ret, make([]layout, colIdx))
-						}
-						if len(ret[rowIdx+i]) == colIdx {
-							// special case for new rows
-							ret[rowIdx+i] = append(ret[rowIdx+i], l)
-						} else {
-							ret[rowIdx+i] = append(ret[rowIdx+i][:colIdx+i], ret[rowIdx+i][colIdx+i-1:]...)
-							ret[rowIdx+i][colIdx] = l
-						}
-					}
-				}
+			if spanHeight > 1 {
+				span.MinHeight /= spanHeight
+				span.MaxHeight /= spanHeight
+				span.PreferredHeight /= spanHeight
+				span.MinHeightPercent /= spanHeight
+				span.MaxHeightPercent /= spanHeight
+				span.PreferredHeightPercent /= spanHeight
 			}
 
-			// horizontal span duplicate handling.
-			spanWidth := ret[rowIdx][colIdx].SpanWidth
-			if spanWidth > 1 && !ret[rowIdx][colIdx].wDuplicate {
-				l := ret[rowIdx][colIdx]
-				// TODO: fix rounding errors?
-				l.MinWidth /= l.SpanWidth
-				l.MaxWidth /= l.SpanWidth
-				l.PreferredWidth /= l.SpanWidth
-				ret[rowIdx][colIdx] = l
-				l.wDuplicate = true
-
-				for i := 1; i < spanWidth; i++ {
-					// make room for a new element
-					ret[rowIdx] = append(ret[rowIdx][:colIdx+i], ret[rowIdx][colIdx+i-1:]...)
-					ret[rowIdx][colIdx+i] = l
+			for y := rowIdx; y < rowIdx+spanHeight; y++ {
+				for x := colIdx; x < colIdx+spanWidth; x++ {
+					grow(y, x)
+					if filled[y][x] {
+						return nil, ErrSpan{ID: l.id, SpanWidth: l.SpanWidth, SpanHeight: l.SpanHeight, Overlap: ret[y][x].id}
+					}
+					c := span
+					c.wDuplicate, c.hDuplicate = x > colIdx, y > rowIdx
+					ret[y][x], filled[y][x] = c, true
 				}
-				// Grow the longest column if necessary.
-				longestCol = max(len(ret[rowIdx]), longestCol)
 			}
+			colIdx += spanWidth
 		}
 	}
 
-	return ret
+	longestRow := 0
+	for _, row := range ret {
+		longestRow = max(longestRow, len(row))
+	}
+	for i := range ret {
+		for len(ret[i]) < longestRow {
+			ret[i] = append(ret[i], layout{})
+		}
+	}
+	return ret, nil
 }
 
 // mergeDocks takes a layout and merges the docked layouts. Returns the new layout and width/height deltas.
@@ -587,17 +2571,29 @@ func mergeDocks(g Grid, docks []layout) Grid {
 	gridWidth := len(g[0])
 
 	// merge docked layouts into the resize cache.
-	for _, d := range docks {
+	for _, d := range orderDocks(docks) {
+		// the border is reserved the same way as it is for cells.
+		dMin, dPref, dMax := reserve(d.Min, d.Preferred, d.Max, 2*d.Dock.Border.size(), 0)
 		switch d.Cardinal {
 		case NORTH:
 			// Cell it to the first row, spanning the entire width.
 			north := layout{
-				id: d.id,
+				id:       d.id,
+				hidden:   d.hidden,
+				hideMode: d.hideMode,
 				Cell: Cell{
-					SpanWidth:       gridWidth,
-					MinHeight:       d.Min,
-					PreferredHeight: d.Preferred,
-					MaxHeight:       d.Max,
+					SpanWidth:              gridWidth,
+					MinHeight:              dMin,
+					PreferredHeight:        dPref,
+					MaxHeight:              dMax,
+					MinHeightPercent:       d.MinPercent,
+					PreferredHeightPercent: d.PreferredPercent,
+					MaxHeightPercent:       d.MaxPercent,
+					Border:                 d.Dock.Border,
+					Layer:                  d.Dock.Layer,
+					GrowHeight:             d.Grow,
+					GrowHeightWeight:       d.GrowWeight,
+					GrowHeightPriority:     d.GrowPriority,
 				},
 			}
 			northRow := make([]layout, 0, gridWidth)
@@ -610,12 +2606,22 @@ func mergeDocks(g Grid, docks []layout) Grid {
 		case SOUTH:
 			// Cell it to the final row, spanning the entire width.
 			south := layout{
-				id: d.id,
+				id:       d.id,
+				hidden:   d.hidden,
+				hideMode: d.hideMode,
 				Cell: Cell{
-					SpanWidth:       gridWidth,
-					MinHeight:       d.Min,
-					PreferredHeight: d.Preferred,
-					MaxHeight:       d.Max,
+					SpanWidth:              gridWidth,
+					MinHeight:              dMin,
+					PreferredHeight:        dPref,
+					MaxHeight:              dMax,
+					MinHeightPercent:       d.MinPercent,
+					PreferredHeightPercent: d.PreferredPercent,
+					MaxHeightPercent:       d.MaxPercent,
+					Border:                 d.Dock.Border,
+					Layer:                  d.Dock.Layer,
+					GrowHeight:             d.Grow,
+					GrowHeightWeight:       d.GrowWeight,
+					GrowHeightPriority:     d.GrowPriority,
 				},
 			}
 			southRow := make([]layout, 0, gridWidth)
@@ -628,12 +2634,22 @@ func mergeDocks(g Grid, docks []layout) Grid {
 		case EAST:
 			// Cell it to the end of each row to span the entire height.
 			east := layout{
-				id: d.id,
+				id:       d.id,
+				hidden:   d.hidden,
+				hideMode: d.hideMode,
 				Cell: Cell{
-					SpanHeight:     gridHeight,
-					MinWidth:       d.Min,
-					PreferredWidth: d.Preferred,
-					MaxWidth:       d.Max,
+					SpanHeight:            gridHeight,
+					MinWidth:              dMin,
+					PreferredWidth:        dPref,
+					MaxWidth:              dMax,
+					MinWidthPercent:       d.MinPercent,
+					PreferredWidthPercent: d.PreferredPercent,
+					MaxWidthPercent:       d.MaxPercent,
+					Border:                d.Dock.Border,
+					Layer:                 d.Dock.Layer,
+					GrowWidth:             d.Grow,
+					GrowWidthWeight:       d.GrowWeight,
+					GrowWidthPriority:     d.GrowPriority,
 				},
 			}
 			for i := 0; i < gridHeight; i++ {
@@ -644,12 +2660,22 @@ func mergeDocks(g Grid, docks []layout) Grid {
 		case WEST:
 			// Cell it to the front of each row to span the entire height.
 			west := layout{
-				id: d.id,
+				id:       d.id,
+				hidden:   d.hidden,
+				hideMode: d.hideMode,
 				Cell: Cell{
-					SpanHeight:     gridHeight,
-					MinWidth:       d.Min,
-					PreferredWidth: d.Preferred,
-					MaxWidth:       d.Max,
+					SpanHeight:            gridHeight,
+					MinWidth:              dMin,
+					PreferredWidth:        dPref,
+					MaxWidth:              dMax,
+					MinWidthPercent:       d.MinPercent,
+					PreferredWidthPercent: d.PreferredPercent,
+					MaxWidthPercent:       d.MaxPercent,
+					Border:                d.Dock.Border,
+					Layer:                 d.Dock.Layer,
+					GrowWidth:             d.Grow,
+					GrowWidthWeight:       d.GrowWeight,
+					GrowWidthPriority:     d.GrowPriority,
 				},
 			}
 			for i := 0; i < gridHeight; i++ {
@@ -664,6 +2690,19 @@ func mergeDocks(g Grid, docks []layout) Grid {
 	return ret
 }
 
+// minNonZero returns the smaller of two values, ignoring zeros which are unset.
+// When cells in a row or column disagree about shrinking, the most reluctant one wins.
+func minNonZero(a, b int) int {
+	switch {
+	case a == 0:
+		return b
+	case b == 0:
+		return a
+	default:
+		return min(a, b)
+	}
+}
+
 // distillPreferences attempts to normalize the different preferences for cells
 // across each row and column.
 //
@@ -699,6 +2738,13 @@ func distillPreferences(g Grid) (hPref, wPref PreferenceGroup) {
 				hPref[rowIdx].Preferred = max(hPref[rowIdx].Preferred, l.PreferredHeight)
 			}
 			hPref[rowIdx].Grow = hPref[rowIdx].Grow || l.GrowHeight
+			hPref[rowIdx].MinPercent = max(hPref[rowIdx].MinPercent, l.MinHeightPercent)
+			hPref[rowIdx].PreferredPercent = max(hPref[rowIdx].PreferredPercent, l.PreferredHeightPercent)
+			hPref[rowIdx].MaxPercent = minNonZero(hPref[rowIdx].MaxPercent, l.MaxHeightPercent)
+			hPref[rowIdx].GrowWeight = max(hPref[rowIdx].GrowWeight, l.GrowHeightWeight)
+			hPref[rowIdx].GrowPriority = max(hPref[rowIdx].GrowPriority, l.GrowHeightPriority)
+			hPref[rowIdx].ShrinkWeight = minNonZero(hPref[rowIdx].ShrinkWeight, l.ShrinkHeightWeight)
+			hPref[rowIdx].ShrinkPriority = minNonZero(hPref[rowIdx].ShrinkPriority, l.ShrinkHeightPriority)
 
 			// collect width preferences
 			if l.MinWidth != 0 {
@@ -715,54 +2761,248 @@ func distillPreferences(g Grid) (hPref, wPref PreferenceGroup) {
 				wPref[colIdx].Preferred = max(wPref[colIdx].Preferred, l.PreferredWidth)
 			}
 			wPref[colIdx].Grow = wPref[colIdx].Grow || l.GrowWidth
+			wPref[colIdx].MinPercent = max(wPref[colIdx].MinPercent, l.MinWidthPercent)
+			wPref[colIdx].PreferredPercent = max(wPref[colIdx].PreferredPercent, l.PreferredWidthPercent)
+			wPref[colIdx].MaxPercent = minNonZero(wPref[colIdx].MaxPercent, l.MaxWidthPercent)
+			wPref[colIdx].GrowWeight = max(wPref[colIdx].GrowWeight, l.GrowWidthWeight)
+			wPref[colIdx].GrowPriority = max(wPref[colIdx].GrowPriority, l.GrowWidthPriority)
+			wPref[colIdx].ShrinkWeight = minNonZero(wPref[colIdx].ShrinkWeight, l.ShrinkWidthWeight)
+			wPref[colIdx].ShrinkPriority = minNonZero(wPref[colIdx].ShrinkPriority, l.ShrinkWidthPriority)
 		}
 	}
 	return
 }
 
 func (bl *bubbleLayout) Validate() error {
+	// names are checked every time, since views can be added to nested layouts without changing this one.
+	if err := bl.checkNames(make(map[string]ID)); err != nil {
+		return err
+	}
 	if len(bl.resizeCache) == 0 {
-		bl.resizeCache = expandSpans(bl.layouts)
-		bl.resizeCache = mergeDocks(bl.resizeCache, bl.docks)
+		if err := bl.validate(); err != nil {
+			// the layout is checked again next time instead of using a broken cache.
+			bl.invalidate()
+			return err
+		}
+	}
+	for _, id := range bl.nestedIDs() {
+		if err := bl.nested[id].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		hPref, wPref := distillPreferences(bl.resizeCache)
+// views returns the cells, docks and positioned views, not including the views of nested layouts.
+func (bl *bubbleLayout) views() []layout {
+	var ret []layout
+	for _, row := range bl.layouts {
+		ret = append(ret, row...)
+	}
+	ret = append(ret, bl.docks...)
+	return append(ret, bl.positioned...)
+}
 
-		// If the user provided constraints are shorter than the auto generated ones, append the distilled ones.
-		// TODO: in the future, cell width/height make this more complicated.
-		// 		 These distilled preferences would need to be merged with the user provided ones.
-		// TODO: this flexibility may not be needed. Should constraints be more strict?
-		appendPref := func(user, distilled PreferenceGroup) PreferenceGroup {
-			if len(user) < len(distilled) {
-				return append(user, distilled[len(user):]...)
-			}
-			return user
+// names returns the IDs of the named views, not including the views of nested layouts.
+func (bl *bubbleLayout) names() map[string]ID {
+	ret := make(map[string]ID)
+	for _, l := range bl.views() {
+		if l.name != "" {
+			ret[l.name] = l.id
 		}
-		bl.hPref = appendPref(bl.hPref, hPref)
-		bl.wPref = appendPref(bl.wPref, wPref)
+	}
+	return ret
+}
 
-		if len(bl.hPref) != len(bl.resizeCache) {
-			return fmt.Errorf("height preferences do not match the cell height")
+// checkNames returns an ErrDuplicateName if a name is used more than once, names contains the names which
+// have already been seen.
+func (bl *bubbleLayout) checkNames(names map[string]ID) error {
+	for _, l := range bl.views() {
+		if l.name == "" {
+			continue
 		}
+		if first, ok := names[l.name]; ok {
+			return ErrDuplicateName{Name: l.name, First: first, Second: l.id}
+		}
+		names[l.name] = l.id
+	}
+	for _, id := range bl.nestedIDs() {
+		if err := bl.nested[id].checkNames(names); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nestedIDs returns the IDs of the cells with nested layouts, in order.
+func (bl *bubbleLayout) nestedIDs() []ID {
+	ids := make([]ID, 0, len(bl.nested))
+	for id := range bl.nested {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
 
-		if len(bl.resizeCache) > 0 && len(bl.wPref) != len(bl.resizeCache[0]) {
-			return fmt.Errorf("width preferences do not match the cell height")
+// alignments returns the maximum size and alignment of the cells which have a maximum size.
+// Percentages are of the width and height of the grid. The views in a size group share the
+// maximum size of the group.
+func (bl *bubbleLayout) alignments(width, height int) map[ID]cellAlignment {
+	ret := make(map[ID]cellAlignment)
+	for _, row := range reconcileSizeGroups(hideLayouts(bl.layouts)) {
+		for _, l := range row {
+			border := 2 * l.Cell.Border.size()
+			a := cellAlignment{
+				maxWidth:  viewMax(l.MaxWidth, l.MaxWidthPercent, width, l.Margin.horizontal(), l.Padding.horizontal()+border),
+				maxHeight: viewMax(l.MaxHeight, l.MaxHeightPercent, height, l.Margin.vertical(), l.Padding.vertical()+border),
+				alignX:    l.AlignX,
+				alignY:    l.AlignY,
+			}
+			if a.maxWidth > 0 || a.maxHeight > 0 {
+				ret[l.id] = a
+			}
 		}
+	}
+	return ret
+}
 
-		return checkPreferenceConstraints(bl.hPref, bl.wPref)
+// mergeNested resizes the nested layouts to the content area of their cell, and adds their views to the message.
+// The views of a nested layout are placed directly after its cell. They are hidden if the cell is hidden.
+func (bl *bubbleLayout) mergeNested(msg *BubbleLayoutMsg) error {
+	if len(bl.nested) == 0 {
+		return nil
+	}
+	order := make([]ID, 0, len(msg.order))
+	for _, id := range msg.order {
+		order = append(order, id)
+		child, ok := bl.nested[id]
+		if !ok {
+			continue
+		}
+		host := msg.views[id]
+		childMsg, err := child.TryResize(host.content.Width, host.content.Height)
+		if err != nil {
+			return err
+		}
+		for _, childID := range childMsg.order {
+			// empty cells are not needed outside of the nested layout.
+			if childID == 0 {
+				continue
+			}
+			p := childMsg.views[childID].translate(host.content.X, host.content.Y)
+			p.hidden = p.hidden || host.hidden
+			// the layers of a nested layout are relative to its cell.
+			p.layer += host.layer
+			msg.views[childID] = &p
+			order = append(order, childID)
+		}
+		for name, childID := range childMsg.names {
+			msg.names[name] = childID
+		}
+		msg.events = append(msg.events, childMsg.events...)
 	}
+	msg.order = order
 	return nil
 }
 
+// validate builds the resize cache and checks the preferences.
+func (bl *bubbleLayout) validate() error {
+	if err := checkLayouts(bl.layouts, bl.docks, bl.positioned); err != nil {
+		return err
+	}
+
+	g := reserveInsets(releaseAligned(reconcileSizeGroups(hideLayouts(bl.layouts))))
+	var err error
+	if bl.options.flowY {
+		// a column major layout is built as a row major layout, then the rows become columns.
+		if g, err = expandSpans(mergeSplits(transposeCells(g))); err != nil {
+			// the spans were transposed with the cells.
+			spanErr := err.(ErrSpan)
+			spanErr.SpanWidth, spanErr.SpanHeight = spanErr.SpanHeight, spanErr.SpanWidth
+			return spanErr
+		}
+		g = transposeGrid(g)
+	} else if g, err = expandSpans(mergeSplits(g)); err != nil {
+		return err
+	}
+	if bl.options.rightToLeft {
+		g = mirrorGrid(g)
+	}
+	bl.resizeCache = mergeDocks(g, hideDocks(bl.docks))
+	bl.hCollapsed, bl.wCollapsed = collapsedRowsAndCols(bl.resizeCache)
+
+	hPref, wPref := distillPreferences(bl.resizeCache)
+
+	// If the user provided constraints are shorter than the auto generated ones, append the distilled ones.
+	// TODO: in the future, cell width/height make this more complicated.
+	// 		 These distilled preferences would need to be merged with the user provided ones.
+	// TODO: this flexibility may not be needed. Should constraints be more strict?
+	appendPref := func(user, distilled PreferenceGroup) PreferenceGroup {
+		if len(user) < len(distilled) {
+			// copy the user constraints so that they can be reused when the cache is invalidated.
+			return append(append(PreferenceGroup{}, user...), distilled[len(user):]...)
+		}
+		return user
+	}
+	bl.hPref = appendPref(bl.hUser, hPref)
+	bl.wPref = appendPref(bl.wUser, wPref)
+
+	if len(bl.hPref) != len(bl.resizeCache) {
+		return ErrPreferenceCount{Axis: Vertical, Expected: len(bl.resizeCache), Received: len(bl.hPref)}
+	}
+
+	if len(bl.resizeCache) > 0 && len(bl.wPref) != len(bl.resizeCache[0]) {
+		return ErrPreferenceCount{Axis: Horizontal, Expected: len(bl.resizeCache[0]), Received: len(bl.wPref)}
+	}
+
+	return checkPreferenceConstraints(bl.hPref, bl.wPref)
+}
+
 // Resize recalculates the layout based on the current terminal size.
 // This function will panic if there is a validation error. If you would like to
-// handle errors, use Validate() before calling Resize().
+// handle errors, use TryResize() instead.
 func (bl *bubbleLayout) Resize(width, height int) BubbleLayoutMsg {
-	if err := bl.Validate(); err != nil {
+	msg, err := bl.TryResize(width, height)
+	if err != nil {
 		panic(err)
 	}
+	return msg
+}
 
-	hDims := bl.hPref.computeDims(height)
-	wDims := bl.wPref.computeDims(width)
+// TryResize is like Resize, except that validation errors are returned instead of causing a panic.
+// The errors are ErrPreferenceConstraint, ErrPreferenceCount, ErrSpan, ErrValue, ErrOption, ErrDock and ErrDuplicateName.
+func (bl *bubbleLayout) TryResize(width, height int) (BubbleLayoutMsg, error) {
+	if err := bl.Validate(); err != nil {
+		return BubbleLayoutMsg{}, err
+	}
 
-	return bl.resizeCache.makeMessage(wDims, hDims)
+	// the insets and gaps are removed before the space is allocated.
+	insets := bl.options.insets
+	hBefore, hAfter := gapsBefore(len(bl.hPref), bl.options.rowGaps, bl.options.gapY, bl.hCollapsed)
+	wBefore, wAfter := gapsBefore(len(bl.wPref), bl.options.colGaps, bl.options.gapX, bl.wCollapsed)
+	hReserved := insets.vertical() + hAfter + sum(hBefore)
+	wReserved := insets.horizontal() + wAfter + sum(wBefore)
+	innerHeight := max(0, height-hReserved)
+	innerWidth := max(0, width-wReserved)
+
+	hDims := bl.hPref.computeVisibleDims(innerHeight, bl.hCollapsed)
+	wDims := bl.wPref.computeVisibleDims(innerWidth, bl.wCollapsed)
+
+	msg := bl.resizeCache.makeMessage(
+		wDims, hDims,
+		clampOffsets(offsets(wDims, wBefore, insets.Left), width),
+		clampOffsets(offsets(hDims, hBefore, insets.Top), height),
+		bl.alignments(innerWidth, innerHeight))
+	msg.width = width
+	msg.height = height
+	msg.placePositioned(bl.positioned)
+	msg.names = bl.names()
+	msg.events = append(
+		bl.wPref.constraintEvents(Horizontal, width, wReserved, wDims, bl.resizeCache.colViews),
+		bl.hPref.constraintEvents(Vertical, height, hReserved, hDims, bl.resizeCache.rowViews)...)
+	if err := bl.mergeNested(&msg); err != nil {
+		return BubbleLayoutMsg{}, err
+	}
+	msg.sortLayers()
+	return msg, nil
 }
@@ -151,6 +151,13 @@ func TestMaybeAdd(t *testing.T) {
 	_, err := l.MaybeAdd("invalid constraint options")
 	require.ErrorContains(t, err, "invalid constraint")
 
+	// empty declarations are an error instead of a panic.
+	for _, in := range []string{"wrap,", "width 4,,grow"} {
+		require.NotPanics(t, func() {
+			_, err = l.MaybeAdd(in)
+		})
+		require.ErrorContains(t, err, "empty constraint", in)
+	}
 }
 
 func TestDockAPI(t *testing.T) {
@@ -285,7 +292,8 @@ func TestResize(t *testing.T) {
 			},
 			out: map[bl.ID]bl.Size{
 				1: {Width: (width / 4) - 1, Height: height},
-				2: {Width: width / 4, Height: height},
+				// the leftover column goes to the first cell which can use it.
+				2: {Width: (width / 4) + 1, Height: height},
 				3: {Width: width / 4, Height: height},
 				4: {Width: width / 4, Height: height},
 			},
@@ -325,3 +333,1529 @@ func TestResize(t *testing.T) {
 		})
 	}
 }
+
+func TestBounds(t *testing.T) {
+	// -------------------------
+	// |   |     NORTH     |   |
+	// |   |----------------   |
+	// | W |   1   |   2   | E |
+	// | E |---------------- A |
+	// | S |       -       | S |
+	// | T | -  -  3  -  - | T |
+	// |   |       -       |   |
+	// |   |----------------   |
+	// |   |     SOUTH     |   |
+	// -------------------------
+	l := bl.New()
+	id1 := l.Add("")
+	id2 := l.Add("wrap")
+	id3 := l.Add("span 2 2")
+	north := l.Add("dock north 2!")
+	south := l.Add("dock south 2!")
+	west := l.Add("dock west 10!")
+	east := l.Add("dock east 10!")
+
+	msg := l.Resize(100, 64)
+	expected := map[bl.ID]bl.Rect{
+		id1:   {X: 10, Y: 2, Width: 40, Height: 20},
+		id2:   {X: 50, Y: 2, Width: 40, Height: 20},
+		id3:   {X: 10, Y: 22, Width: 80, Height: 40},
+		north: {X: 10, Y: 0, Width: 80, Height: 2},
+		south: {X: 10, Y: 62, Width: 80, Height: 2},
+		west:  {X: 0, Y: 0, Width: 10, Height: 64},
+		east:  {X: 90, Y: 0, Width: 10, Height: 64},
+	}
+	for id, rect := range expected {
+		actual, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, rect, actual, "id %d", id)
+		size, err := msg.Size(id)
+		require.NoError(t, err)
+		assert.Equal(t, rect.Size(), size)
+	}
+
+	_, err := msg.Bounds(100)
+	require.EqualError(t, err, "view not registered")
+}
+
+func TestHitTest(t *testing.T) {
+	// -------------------
+	// |      NORTH      |
+	// -------------------
+	// |  1  |  2  |     |
+	// -------------     |
+	// |     3     |  E  |
+	// |           |     |
+	// -------------------
+	l := bl.New()
+	id1 := l.Add("")
+	id2 := l.Add("wrap")
+	id3 := l.Add("span 2 2")
+	east := l.Add("dock east 10!")
+	north := l.Add("dock north 2!")
+
+	msg := l.Resize(30, 14)
+	testcases := []struct {
+		x, y     int
+		expected bl.ID
+		ok       bool
+	}{
+		{x: 0, y: 0, expected: north, ok: true},
+		{x: 29, y: 1, expected: north, ok: true},
+		{x: 0, y: 2, expected: id1, ok: true},
+		{x: 9, y: 5, expected: id1, ok: true},
+		{x: 10, y: 5, expected: id2, ok: true},
+		{x: 0, y: 6, expected: id3, ok: true},
+		{x: 19, y: 13, expected: id3, ok: true},
+		{x: 20, y: 2, expected: east, ok: true},
+		{x: 29, y: 13, expected: east, ok: true},
+		{x: 30, y: 0},
+		{x: 0, y: 14},
+		{x: -1, y: 0},
+	}
+	for _, tc := range testcases {
+		id, ok := msg.HitTest(tc.x, tc.y)
+		assert.Equal(t, tc.ok, ok, "(%d, %d)", tc.x, tc.y)
+		assert.Equal(t, tc.expected, id, "(%d, %d)", tc.x, tc.y)
+	}
+}
+
+func TestHitTest_EmptyCell(t *testing.T) {
+	l := bl.New()
+	l.Add("wrap")
+	l.Add("")
+	id3 := l.Add("")
+
+	msg := l.Resize(10, 10)
+	id, ok := msg.HitTest(7, 2)
+	assert.False(t, ok)
+	assert.Equal(t, bl.ID(0), id)
+
+	id, ok = msg.HitTest(7, 7)
+	assert.True(t, ok)
+	assert.Equal(t, id3, id)
+}
+
+func TestPaddingAndMargin(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("pad 1 2")
+	id2 := l.Add("margin 1, pad 1")
+	id3 := l.Add("gapx 0 4, width 10!")
+
+	msg := l.Resize(40, 10)
+	testcases := []struct {
+		id      bl.ID
+		bounds  bl.Rect
+		content bl.Rect
+	}{
+		{id: id1, bounds: bl.Rect{X: 0, Y: 0, Width: 13, Height: 10}, content: bl.Rect{X: 2, Y: 1, Width: 9, Height: 8}},
+		{id: id2, bounds: bl.Rect{X: 14, Y: 1, Width: 11, Height: 8}, content: bl.Rect{X: 15, Y: 2, Width: 9, Height: 6}},
+		{id: id3, bounds: bl.Rect{X: 26, Y: 0, Width: 10, Height: 10}, content: bl.Rect{X: 26, Y: 0, Width: 10, Height: 10}},
+	}
+	for _, tc := range testcases {
+		bounds, err := msg.Bounds(tc.id)
+		require.NoError(t, err)
+		assert.Equal(t, tc.bounds, bounds, "bounds %d", tc.id)
+
+		content, err := msg.Content(tc.id)
+		require.NoError(t, err)
+		assert.Equal(t, tc.content, content, "content %d", tc.id)
+	}
+
+	_, err := msg.Content(100)
+	require.EqualError(t, err, "view not registered")
+}
+
+func TestPaddingReservesSpace(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("pad 0 3")
+	id2 := l.Add("grow")
+
+	msg := l.Resize(40, 10)
+	content, err := msg.Content(id1)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{X: 3, Y: 0, Width: 0, Height: 10}, content)
+
+	bounds, err := msg.Bounds(id2)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{X: 6, Y: 0, Width: 34, Height: 10}, bounds)
+}
+
+func TestPaddingWithSmallSize(t *testing.T) {
+	// sizes include the padding and border, a smaller size is raised to fit them.
+	testcases := []struct {
+		name    string
+		in      []string
+		bounds  bl.Rect
+		content bl.Rect
+	}{
+		{
+			name:    "padding and preferred width",
+			in:      []string{"pad 3, width 4", "grow"},
+			bounds:  bl.Rect{Width: 6, Height: 10},
+			content: bl.Rect{X: 3, Y: 3, Width: 0, Height: 4},
+		}, {
+			name:    "padding and fixed width",
+			in:      []string{"pad 1, width 4!", "grow"},
+			bounds:  bl.Rect{Width: 4, Height: 10},
+			content: bl.Rect{X: 1, Y: 1, Width: 2, Height: 8},
+		}, {
+			name:    "border and small width",
+			in:      []string{"border, width 1", "grow"},
+			bounds:  bl.Rect{Width: 2, Height: 10},
+			content: bl.Rect{X: 1, Y: 1, Width: 0, Height: 8},
+		}, {
+			name:    "border and fixed height",
+			in:      []string{"border, height 1!, wrap", "grow"},
+			bounds:  bl.Rect{Width: 20, Height: 2},
+			content: bl.Rect{X: 1, Y: 1, Width: 18, Height: 0},
+		}, {
+			name:    "padding in a split cell",
+			in:      []string{"split 2, pad 0 2, width 5", "width 3!", "grow"},
+			bounds:  bl.Rect{Width: 5, Height: 10},
+			content: bl.Rect{X: 2, Width: 1, Height: 10},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := bl.New()
+			var ids []bl.ID
+			for _, in := range tc.in {
+				ids = append(ids, l.Add(in))
+			}
+			msg, err := l.TryResize(20, 10)
+			require.NoError(t, err)
+			bounds, err := msg.Bounds(ids[0])
+			require.NoError(t, err)
+			assert.Equal(t, tc.bounds, bounds)
+			content, err := msg.Content(ids[0])
+			require.NoError(t, err)
+			assert.Equal(t, tc.content, content)
+		})
+	}
+}
+
+func TestSetVisible_Reserve(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("")
+	id2 := l.Add("hidden")
+
+	msg := l.Resize(80, 10)
+	assert.False(t, msg.Hidden(id1))
+	assert.True(t, msg.Hidden(id2))
+	bounds, err := msg.Bounds(id2)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{X: 40, Y: 0}, bounds)
+	size, err := msg.Size(id1)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Size{Width: 40, Height: 10}, size)
+
+	// the hidden view is empty when rendered.
+	_, ok := msg.HitTest(50, 5)
+	assert.False(t, ok)
+
+	l.SetVisible(id2, true)
+	msg = l.Resize(80, 10)
+	assert.False(t, msg.Hidden(id2))
+	size, err = msg.Size(id2)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Size{Width: 40, Height: 10}, size)
+}
+
+func TestSetVisible_Collapse(t *testing.T) {
+	// -----------------
+	// |  help (north) |
+	// -----------------
+	// | side | editor |
+	// -----------------
+	l := bl.New()
+	side := l.Add("width 20, hidemode collapse")
+	editor := l.Add("grow")
+	help := l.Add("dock north 3!, hidemode collapse")
+
+	msg := l.Resize(80, 20)
+	expected := map[bl.ID]bl.Rect{
+		side:   {X: 0, Y: 3, Width: 20, Height: 17},
+		editor: {X: 20, Y: 3, Width: 60, Height: 17},
+		help:   {X: 0, Y: 0, Width: 80, Height: 3},
+	}
+	for id, rect := range expected {
+		actual, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, rect, actual, "id %d", id)
+	}
+
+	// The IDs are unchanged after hiding, the editor takes all of the space.
+	l.SetVisible(side, false)
+	l.SetVisible(help, false)
+	msg = l.Resize(80, 20)
+	expected = map[bl.ID]bl.Rect{
+		side:   {X: 0, Y: 0},
+		editor: {X: 0, Y: 0, Width: 80, Height: 20},
+		help:   {X: 0, Y: 0},
+	}
+	for id, rect := range expected {
+		actual, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, rect, actual, "id %d", id)
+	}
+	assert.True(t, msg.Hidden(side))
+	assert.True(t, msg.Hidden(help))
+	assert.False(t, msg.Hidden(editor))
+
+	// Unknown IDs are ignored.
+	l.SetVisible(100, false)
+	require.NoError(t, l.Validate())
+}
+
+func TestSetVisible_CollapseSharedColumn(t *testing.T) {
+	// a column only collapses if everything in it is hidden.
+	l := bl.New()
+	id1 := l.Add("hidemode collapse")
+	l.Add("wrap")
+	id3 := l.Add("")
+	l.Add("")
+
+	l.SetVisible(id1, false)
+	msg := l.Resize(80, 20)
+	size, err := msg.Size(id3)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Size{Width: 40, Height: 10}, size)
+}
+
+func TestSetVisible_UserConstraints(t *testing.T) {
+	// user constraints are reused when the layout is recalculated.
+	l := bl.NewWithConstraints(bl.PreferenceGroup{{Preferred: 10}}, nil)
+	id1 := l.Add("")
+	id2 := l.Add("hidemode collapse")
+	id3 := l.Add("")
+
+	msg := l.Resize(80, 10)
+	size, err := msg.Size(id1)
+	require.NoError(t, err)
+	assert.Equal(t, 10, size.Width)
+
+	l.SetVisible(id2, false)
+	msg = l.Resize(80, 10)
+	size, err = msg.Size(id1)
+	require.NoError(t, err)
+	assert.Equal(t, 10, size.Width)
+	size, err = msg.Size(id3)
+	require.NoError(t, err)
+	assert.Equal(t, 70, size.Width)
+}
+
+func TestResize_Undersized(t *testing.T) {
+	// the sidebar gives up space before the editor does.
+	l := bl.New()
+	sidebar := l.Add("width 10:30, shrinkprio 200")
+	editor := l.Add("width 20:60")
+
+	testcases := []struct {
+		width   int
+		sidebar int
+		editor  int
+	}{
+		{width: 90, sidebar: 30, editor: 60},
+		{width: 80, sidebar: 20, editor: 60},
+		{width: 70, sidebar: 10, editor: 60},
+		{width: 50, sidebar: 10, editor: 40},
+		{width: 30, sidebar: 10, editor: 20},
+		{width: 20, sidebar: 0, editor: 20},
+	}
+
+	for _, tc := range testcases {
+		msg := l.Resize(tc.width, 10)
+		size, err := msg.Size(sidebar)
+		require.NoError(t, err)
+		assert.Equal(t, tc.sidebar, size.Width, "sidebar width at %d", tc.width)
+		size, err = msg.Size(editor)
+		require.NoError(t, err)
+		assert.Equal(t, tc.editor, size.Width, "editor width at %d", tc.width)
+	}
+}
+
+func TestResize_ShrinkWeights(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("width 40, shrinkx 100")
+	id2 := l.Add("width 40, shrinkx 300")
+	id3 := l.Add("width 10, shrink 0")
+
+	msg := l.Resize(50, 10)
+	for id, width := range map[bl.ID]int{id1: 30, id2: 10, id3: 10} {
+		size, err := msg.Size(id)
+		require.NoError(t, err)
+		assert.Equal(t, width, size.Width, "id %d", id)
+	}
+}
+
+func TestResize_GrowWeights(t *testing.T) {
+	// a 2:1 main to detail split.
+	l := bl.New()
+	main := l.Add("grow 200")
+	detail := l.Add("grow 100")
+
+	msg := l.Resize(90, 10)
+	size, err := msg.Size(main)
+	require.NoError(t, err)
+	assert.Equal(t, 60, size.Width)
+	size, err = msg.Size(detail)
+	require.NoError(t, err)
+	assert.Equal(t, 30, size.Width)
+}
+
+func TestResize_GrowPriority(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("width 10, growx")
+	id2 := l.Add("width 10:10:40, growx, growpriox 200")
+
+	msg := l.Resize(80, 10)
+	size, err := msg.Size(id1)
+	require.NoError(t, err)
+	assert.Equal(t, 40, size.Width)
+	size, err = msg.Size(id2)
+	require.NoError(t, err)
+	assert.Equal(t, 40, size.Width)
+}
+
+func TestResize_Percentages(t *testing.T) {
+	l := bl.New()
+	sidebar := l.Add("width 25%")
+	content := l.Add("width 1fr")
+	detail := l.Add("width 10:n:20%, growx")
+
+	testcases := []struct {
+		width   int
+		sidebar int
+		content int
+		detail  int
+	}{
+		{width: 80, sidebar: 20, content: 44, detail: 16},
+		{width: 300, sidebar: 75, content: 165, detail: 60},
+	}
+
+	for _, tc := range testcases {
+		msg := l.Resize(tc.width, 10)
+		for id, width := range map[bl.ID]int{sidebar: tc.sidebar, content: tc.content, detail: tc.detail} {
+			size, err := msg.Size(id)
+			require.NoError(t, err)
+			assert.Equal(t, width, size.Width, "id %d at width %d", id, tc.width)
+		}
+	}
+}
+
+func TestEvents_Overallocated(t *testing.T) {
+	l := bl.New()
+	l.Add("width 30:40")
+	id2 := l.Add("width 30:40")
+	l.Wrap()
+	id3 := l.Add("height 5!, span 2")
+
+	msg := l.Resize(50, 20)
+	require.True(t, msg.Overallocated())
+	require.Equal(t, []bl.ConstraintEvent{
+		bl.SpaceOverallocated{Axis: bl.Horizontal, Needed: 60, Available: 50, IDs: []bl.ID{id2, id3}},
+	}, msg.Events())
+
+	msg = l.Resize(80, 4)
+	require.True(t, msg.Overallocated())
+	require.Equal(t, []bl.ConstraintEvent{
+		bl.SpaceOverallocated{Axis: bl.Vertical, Needed: 5, Available: 4, IDs: []bl.ID{id3}},
+	}, msg.Events())
+
+	msg = l.Resize(80, 20)
+	require.False(t, msg.Overallocated())
+	require.Empty(t, msg.Events())
+}
+
+func TestEvents_Unallocated(t *testing.T) {
+	l := bl.New()
+	l.Add("width 10!")
+	l.Add("width 20:20:30")
+
+	msg := l.Resize(80, 10)
+	require.False(t, msg.Overallocated())
+	require.Equal(t, []bl.ConstraintEvent{
+		bl.UnallocatedSpace{Axis: bl.Horizontal, Unallocated: 50},
+	}, msg.Events())
+}
+
+func TestTryResize(t *testing.T) {
+	l := bl.New()
+	id := l.Add("width 10")
+	msg, err := l.TryResize(80, 10)
+	require.NoError(t, err)
+	size, err := msg.Size(id)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Size{Width: 10, Height: 10}, size)
+}
+
+func TestTryResize_Errors(t *testing.T) {
+	testcases := []struct {
+		name string
+		in   func() bl.BubbleLayout
+		err  error
+	}{
+		{
+			name: "preference constraint",
+			in: func() bl.BubbleLayout {
+				l := bl.New()
+				l.Cell(bl.Cell{MinHeight: 100})
+				l.Cell(bl.Cell{MaxHeight: 10})
+				return l
+			},
+			err: bl.ErrPreferenceConstraint{Row: true, Index: 0, Min: 100, Max: 10},
+		}, {
+			name: "preference count",
+			in: func() bl.BubbleLayout {
+				return bl.NewWithConstraints(bl.PreferenceGroup{{}}, bl.PreferenceGroup{{}})
+			},
+			err: bl.ErrPreferenceCount{Axis: bl.Horizontal, Expected: 0, Received: 1},
+		}, {
+			name: "negative span",
+			in: func() bl.BubbleLayout {
+				l := bl.New()
+				l.Cell(bl.Cell{SpanWidth: -1})
+				return l
+			},
+			err: bl.ErrSpan{ID: 1, SpanWidth: -1},
+		}, {
+			name: "invalid dock",
+			in: func() bl.BubbleLayout {
+				l := bl.New()
+				l.Add("")
+				l.Dock(bl.Dock{Cardinal: "up"})
+				return l
+			},
+			err: bl.ErrDock{ID: 2, Cardinal: "up"},
+		}, {
+			name: "negative shrink weight",
+			in: func() bl.BubbleLayout {
+				l := bl.New()
+				l.Cell(bl.Cell{ShrinkWidthWeight: bl.NoShrink})
+				l.Cell(bl.Cell{ShrinkWidthWeight: -2})
+				return l
+			},
+			err: bl.ErrValue{ID: 2, Field: "ShrinkWidthWeight", Value: -2},
+		}, {
+			name: "negative padding",
+			in: func() bl.BubbleLayout {
+				l := bl.New()
+				l.Cell(bl.Cell{Padding: bl.Insets{Left: -3}})
+				return l
+			},
+			err: bl.ErrValue{ID: 1, Field: "Padding.Left", Value: -3},
+		}, {
+			name: "negative margin",
+			in: func() bl.BubbleLayout {
+				l := bl.New()
+				l.Cell(bl.Cell{Margin: bl.Insets{Top: -1}})
+				return l
+			},
+			err: bl.ErrValue{ID: 1, Field: "Margin.Top", Value: -1},
+		}, {
+			name: "negative dock size",
+			in: func() bl.BubbleLayout {
+				l := bl.New()
+				l.Add("")
+				l.Dock(bl.Dock{Cardinal: bl.NORTH, Preferred: -1})
+				return l
+			},
+			err: bl.ErrValue{ID: 2, Field: "Preferred", Value: -1},
+		}, {
+			name: "unknown border",
+			in: func() bl.BubbleLayout {
+				l := bl.New()
+				l.Cell(bl.Cell{Border: "bogus"})
+				return l
+			},
+			err: bl.ErrOption{ID: 1, Field: "Border", Value: "bogus"},
+		}, {
+			name: "unknown dock border",
+			in: func() bl.BubbleLayout {
+				l := bl.New()
+				l.Add("")
+				l.Dock(bl.Dock{Cardinal: bl.NORTH, Border: "bogus"})
+				return l
+			},
+			err: bl.ErrOption{ID: 2, Field: "Border", Value: "bogus"},
+		}, {
+			name: "unknown positioned border",
+			in: func() bl.BubbleLayout {
+				l := bl.New()
+				l.Pos(bl.Pos{Border: "bogus"})
+				return l
+			},
+			err: bl.ErrOption{ID: 1, Field: "Border", Value: "bogus"},
+		}, {
+			name: "unknown horizontal alignment",
+			in: func() bl.BubbleLayout {
+				l := bl.New()
+				l.Cell(bl.Cell{MaxWidth: 5, AlignX: bl.AlignTop})
+				return l
+			},
+			err: bl.ErrOption{ID: 1, Field: "AlignX", Value: "top"},
+		}, {
+			name: "unknown vertical alignment",
+			in: func() bl.BubbleLayout {
+				l := bl.New()
+				l.Cell(bl.Cell{MaxHeight: 5, AlignY: "middle"})
+				return l
+			},
+			err: bl.ErrOption{ID: 1, Field: "AlignY", Value: "middle"},
+		}, {
+			name: "overlapping span",
+			in: func() bl.BubbleLayout {
+				l := bl.New()
+				l.Add("")
+				l.Add("spanh 2, wrap")
+				l.Add("spanw 2")
+				return l
+			},
+			err: bl.ErrSpan{ID: 3, SpanWidth: 2, Overlap: 2},
+		}, {
+			name: "overlapping span in a column major layout",
+			in: func() bl.BubbleLayout {
+				l := bl.New("flowy")
+				l.Add("")
+				l.Add("spanw 2, wrap")
+				l.Add("spanh 2")
+				return l
+			},
+			err: bl.ErrSpan{ID: 3, SpanHeight: 2, Overlap: 2},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			l := tc.in()
+			require.NotPanics(t, func() {
+				_, err := l.TryResize(80, 10)
+				require.ErrorIs(t, err, tc.err)
+			})
+			// the error is returned again instead of using a partially built layout.
+			require.ErrorIs(t, l.Validate(), tc.err)
+		})
+	}
+}
+
+func TestErrPreferenceConstraint(t *testing.T) {
+	assert.EqualError(t, bl.ErrPreferenceConstraint{Row: true, Index: 1, Min: 100, Max: 10},
+		"constraint violation: row 1: Min height (100), Preferred height (0) Max height (10)")
+	assert.EqualError(t, bl.ErrPreferenceConstraint{Index: 2, Min: 5, Preferred: 4, Max: 3},
+		"constraint violation: col 2: Min width (5), Preferred width (4) Max width (3)")
+}
+
+func TestErrOption(t *testing.T) {
+	assert.EqualError(t, bl.ErrOption{ID: 1, Field: "Border", Value: "bogus"}, "invalid Border for view 1: 'bogus'")
+}
+
+func TestErrSpan(t *testing.T) {
+	assert.EqualError(t, bl.ErrSpan{ID: 1, SpanWidth: -1}, "invalid span for view 1: spans must not be negative, received -1 0")
+	assert.EqualError(t, bl.ErrSpan{ID: 3, SpanWidth: 2, Overlap: 2}, "invalid span for view 3: span 2 0 overlaps the span of view 2")
+}
+
+func TestSpan_Wrapped(t *testing.T) {
+	l := bl.New()
+	left := l.Add("spanh 2, wrap")
+	id2 := l.Add("")
+	id3 := l.Add("")
+
+	// the cells on the next row are placed after the span.
+	msg, err := l.TryResize(30, 10)
+	require.NoError(t, err)
+	expected := map[bl.ID]bl.Rect{
+		left: {X: 0, Y: 0, Width: 10, Height: 10},
+		id2:  {X: 10, Y: 5, Width: 10, Height: 5},
+		id3:  {X: 20, Y: 5, Width: 10, Height: 5},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	l := bl.New()
+	toolbar := []bl.ID{
+		l.Add("split 3, span 2, width 10!, height 1!"),
+		l.Add("width 10!"),
+		l.Add("growx, wrap"),
+	}
+	side := l.Add("width 20!")
+	content := l.Add("grow")
+
+	msg := l.Resize(80, 10)
+	expected := map[bl.ID]bl.Rect{
+		toolbar[0]: {X: 0, Y: 0, Width: 10, Height: 1},
+		toolbar[1]: {X: 10, Y: 0, Width: 10, Height: 1},
+		toolbar[2]: {X: 20, Y: 0, Width: 60, Height: 1},
+		side:       {X: 0, Y: 1, Width: 20, Height: 9},
+		content:    {X: 20, Y: 1, Width: 60, Height: 9},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+}
+
+func TestSplit_MixedPreferences(t *testing.T) {
+	// the view without a preference takes the rest of the split cell.
+	l := bl.New()
+	id1 := l.Add("split 2, width 4")
+	id2 := l.Add("")
+	id3 := l.Add("")
+
+	msg := l.Resize(12, 1)
+	expected := map[bl.ID]bl.Rect{
+		id1: {X: 0, Width: 4, Height: 1},
+		id2: {X: 4, Width: 2, Height: 1},
+		id3: {X: 6, Width: 6, Height: 1},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+
+	l = bl.New()
+	id1 = l.Add("split 3")
+	id2 = l.Add("width 2!")
+	id3 = l.Add("")
+
+	msg = l.Resize(12, 1)
+	expected = map[bl.ID]bl.Rect{
+		id1: {X: 0, Width: 5, Height: 1},
+		id2: {X: 5, Width: 2, Height: 1},
+		id3: {X: 7, Width: 5, Height: 1},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+}
+
+func TestNest(t *testing.T) {
+	parent := bl.New()
+	sidebar := parent.Add("width 20!")
+	child := bl.New()
+	host := parent.Nest(child, "grow, border")
+	status := parent.Add("dock south 1!")
+
+	// views are added to the child after it is nested.
+	header := child.Add("height 3!, wrap")
+	body := child.Add("grow")
+	require.NotContains(t, []bl.ID{sidebar, host, status}, header)
+	require.NotContains(t, []bl.ID{sidebar, host, status}, body)
+
+	msg := parent.Resize(80, 20)
+	expected := map[bl.ID]bl.Rect{
+		sidebar: {X: 0, Y: 0, Width: 20, Height: 19},
+		host:    {X: 20, Y: 0, Width: 60, Height: 19},
+		status:  {X: 0, Y: 19, Width: 80, Height: 1},
+		header:  {X: 21, Y: 1, Width: 58, Height: 3},
+		body:    {X: 21, Y: 4, Width: 58, Height: 14},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+
+	id, ok := msg.HitTest(30, 10)
+	require.True(t, ok)
+	assert.Equal(t, body, id)
+	id, ok = msg.HitTest(20, 10)
+	require.True(t, ok)
+	assert.Equal(t, host, id)
+}
+
+func TestNest_SetVisible(t *testing.T) {
+	parent := bl.New()
+	child := bl.New()
+	host := parent.Nest(child, "grow")
+	id1 := child.Add("hidemode collapse")
+	id2 := child.Add("")
+
+	msg := parent.Resize(80, 10)
+	size, err := msg.Size(id2)
+	require.NoError(t, err)
+	assert.Equal(t, 40, size.Width)
+
+	// views in the child can be hidden through the parent.
+	parent.SetVisible(id1, false)
+	msg = parent.Resize(80, 10)
+	require.True(t, msg.Hidden(id1))
+	size, err = msg.Size(id2)
+	require.NoError(t, err)
+	assert.Equal(t, 80, size.Width)
+
+	// hiding the cell hides the nested views.
+	parent.SetVisible(host, false)
+	msg = parent.Resize(80, 10)
+	require.True(t, msg.Hidden(id2))
+}
+
+func TestNest_Errors(t *testing.T) {
+	parent := bl.New()
+
+	used := bl.New()
+	used.Add("")
+	_, err := parent.MaybeNest(used, "")
+	require.ErrorContains(t, err, "it must be empty")
+
+	child := bl.New()
+	parent.Nest(child, "")
+	_, err = bl.New().MaybeNest(child, "")
+	require.ErrorContains(t, err, "it is already nested")
+	_, err = parent.MaybeNest(parent, "")
+	require.ErrorContains(t, err, "cannot be nested in itself")
+
+	_, err = parent.MaybeNest(bl.New(), "dock north")
+	require.ErrorContains(t, err, "it cannot be docked")
+
+	_, err = parent.MaybeNest(bl.New(), "invalid constraint")
+	require.ErrorContains(t, err, "unknown constraint")
+
+	require.Panics(t, func() { parent.Nest(used, "") })
+}
+
+func TestNest_ValidationError(t *testing.T) {
+	parent := bl.New()
+	child := bl.New()
+	parent.Nest(child, "")
+	child.Cell(bl.Cell{SpanWidth: -1})
+
+	_, err := parent.TryResize(80, 10)
+	require.ErrorIs(t, err, bl.ErrSpan{ID: 2, SpanWidth: -1})
+}
+
+func TestFlowY(t *testing.T) {
+	l := bl.New("flowy")
+	id1 := l.Add("height 3!")
+	id2 := l.Add("grow, wrap")
+	id3 := l.Add("width 10!, spany 2")
+	id4 := l.Add("dock north 1!")
+
+	msg := l.Resize(80, 11)
+	expected := map[bl.ID]bl.Rect{
+		id1: {X: 0, Y: 1, Width: 70, Height: 3},
+		id2: {X: 0, Y: 4, Width: 70, Height: 7},
+		id3: {X: 70, Y: 1, Width: 10, Height: 10},
+		id4: {X: 0, Y: 0, Width: 80, Height: 1},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+}
+
+func TestFlowY_Split(t *testing.T) {
+	l := bl.New("flowy")
+	// the split cell is placed top to bottom.
+	id1 := l.Add("split 2, height 2!")
+	id2 := l.Add("grow")
+	id3 := l.Add("height 5!")
+
+	msg := l.Resize(80, 10)
+	expected := map[bl.ID]bl.Rect{
+		id1: {X: 0, Y: 0, Width: 80, Height: 2},
+		id2: {X: 0, Y: 2, Width: 80, Height: 3},
+		id3: {X: 0, Y: 5, Width: 80, Height: 5},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+}
+
+func TestRightToLeft(t *testing.T) {
+	l := bl.New("rtl")
+	id1 := l.Add("width 10!")
+	id2 := l.Add("split 2, growx")
+	id3 := l.Add("width 5!, wrap")
+	id4 := l.Add("span 2, margin 0 1 0 2")
+	id5 := l.Add("dock east 3!")
+
+	msg := l.Resize(80, 10)
+	expected := map[bl.ID]bl.Rect{
+		id1: {X: 67, Y: 0, Width: 10, Height: 5},
+		id2: {X: 5, Y: 0, Width: 62, Height: 5},
+		id3: {X: 0, Y: 0, Width: 5, Height: 5},
+		id4: {X: 2, Y: 5, Width: 74, Height: 5},
+		id5: {X: 77, Y: 0, Width: 3, Height: 10},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+}
+
+func TestRowColumnConstraints(t *testing.T) {
+	l := bl.New("insets 1, gap 1", "[20!]2[grow][30:n:50]", "[3!][grow]")
+	id1 := l.Add("")
+	id2 := l.Add("")
+	id3 := l.Add("wrap")
+	id4 := l.Add("span 3")
+
+	msg := l.Resize(100, 20)
+	expected := map[bl.ID]bl.Rect{
+		id1: {X: 1, Y: 1, Width: 20, Height: 3},
+		id2: {X: 23, Y: 1, Width: 25, Height: 3},
+		// without a preferred size the column expands to its maximum before growing.
+		id3: {X: 49, Y: 1, Width: 50, Height: 3},
+		// spanning cells include the gaps between the columns.
+		id4: {X: 1, Y: 5, Width: 98, Height: 14},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+	assert.Empty(t, msg.Events())
+}
+
+func TestRowColumnConstraints_Collapsed(t *testing.T) {
+	l := bl.New("gapx 2", "[10!][10!][10!]")
+	id1 := l.Add("")
+	id2 := l.Add("hidden, hidemode collapse")
+	id3 := l.Add("")
+
+	msg := l.Resize(40, 1)
+	bounds, err := msg.Bounds(id1)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{X: 0, Y: 0, Width: 10, Height: 1}, bounds)
+	// there is only one gap because the middle column collapsed.
+	bounds, err = msg.Bounds(id3)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{X: 12, Y: 0, Width: 10, Height: 1}, bounds)
+	_, err = msg.Bounds(id2)
+	require.NoError(t, err)
+}
+
+func TestRowColumnConstraints_InsetsTooLarge(t *testing.T) {
+	l := bl.New("insets 5, gap 10")
+	id1 := l.Add("")
+	id2 := l.Add("")
+
+	// the views are kept inside of the layout when the insets and gaps do not fit.
+	msg := l.Resize(10, 5)
+	expected := map[bl.ID]bl.Rect{
+		id1: {X: 5, Y: 5},
+		id2: {X: 10, Y: 5},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+	require.True(t, msg.Overallocated())
+	assert.Equal(t, []bl.ConstraintEvent{
+		bl.SpaceOverallocated{Axis: bl.Horizontal, Needed: 20, Available: 10, IDs: []bl.ID{id1, id2}},
+		bl.SpaceOverallocated{Axis: bl.Vertical, Needed: 10, Available: 5, IDs: []bl.ID{id1, id2}},
+	}, msg.Events())
+
+	msg = l.Resize(30, 20)
+	require.False(t, msg.Overallocated())
+}
+
+func TestPos(t *testing.T) {
+	l := bl.New()
+	content := l.Add("")
+	status := l.Add("south 1!")
+	modal := l.Add("pos center center 40 10, border")
+	toast := l.Pos(bl.Pos{X: -1, XPercent: 100, Y: -1, YPercent: 100, Width: 20, Height: 3})
+
+	msg := l.Resize(100, 40)
+	expected := map[bl.ID]bl.Rect{
+		content: {X: 0, Y: 0, Width: 100, Height: 39},
+		status:  {X: 0, Y: 39, Width: 100, Height: 1},
+		modal:   {X: 30, Y: 15, Width: 40, Height: 10},
+		toast:   {X: 79, Y: 36, Width: 20, Height: 3},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+	c, err := msg.Content(modal)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{X: 31, Y: 16, Width: 38, Height: 8}, c)
+
+	// positioned views are on top of the grid.
+	id, ok := msg.HitTest(50, 20)
+	require.True(t, ok)
+	assert.Equal(t, modal, id)
+
+	l.SetVisible(modal, false)
+	msg = l.Resize(100, 40)
+	assert.True(t, msg.Hidden(modal))
+	id, ok = msg.HitTest(50, 20)
+	require.True(t, ok)
+	assert.Equal(t, content, id)
+}
+
+func TestPos_Nest(t *testing.T) {
+	l := bl.New()
+	l.Add("")
+	popup := bl.New()
+	l.Nest(popup, "pos 10 5 20 10")
+	item1 := popup.Add("wrap")
+	item2 := popup.Add("")
+
+	msg := l.Resize(100, 40)
+	bounds, err := msg.Bounds(item1)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{X: 10, Y: 5, Width: 20, Height: 5}, bounds)
+	bounds, err = msg.Bounds(item2)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{X: 10, Y: 10, Width: 20, Height: 5}, bounds)
+}
+
+func TestLayers(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("layer 1")
+	id2 := l.Add("")
+	status := l.Add("south 1!, layer 2")
+	tooltip := l.Pos(bl.Pos{X: 0, Y: 0, Width: 10, Height: 1, Layer: 3})
+	background := l.Add("pos 0 0, layer -1")
+
+	msg := l.Resize(20, 10)
+	assert.Equal(t, []bl.ID{background, id2, id1, status, tooltip}, msg.Order())
+	layer, err := msg.Layer(status)
+	require.NoError(t, err)
+	assert.Equal(t, 2, layer)
+	_, err = msg.Layer(100)
+	require.Error(t, err)
+
+	// the tooltip is on top of the first cell, the background is below everything.
+	id, ok := msg.HitTest(0, 0)
+	require.True(t, ok)
+	assert.Equal(t, tooltip, id)
+	id, ok = msg.HitTest(0, 1)
+	require.True(t, ok)
+	assert.Equal(t, id1, id)
+	id, ok = msg.HitTest(15, 5)
+	require.True(t, ok)
+	assert.Equal(t, id2, id)
+}
+
+func TestLayers_Nest(t *testing.T) {
+	l := bl.New()
+	child := bl.New()
+	host := l.Nest(child, "layer 2")
+	id2 := l.Add("layer 1")
+	id3 := child.Add("")
+	id4 := child.Add("layer -1")
+
+	// nested layers are relative to the cell of the nested layout.
+	msg := l.Resize(20, 10)
+	layer, err := msg.Layer(id4)
+	require.NoError(t, err)
+	assert.Equal(t, 1, layer)
+	assert.Equal(t, []bl.ID{id4, id2, host, id3}, msg.Order())
+}
+
+func TestAlign(t *testing.T) {
+	l := bl.New()
+	wide := l.Add("width 40, height 2!, wrap")
+	left := l.Add("width :n:10, align left, height 2!, wrap")
+	center := l.Add("width :n:10, align center, height 2!, wrap")
+	right := l.Add("width :n:10, height 1:n:3, pad 1, align right bottom, growy, wrap")
+	host := l.Add("width 20, split 2, height 2!")
+	split := l.Add("width :n:10, align center")
+
+	// the aligned views do not limit the width of the column.
+	msg := l.Resize(40, 20)
+	expected := map[bl.ID]bl.Rect{
+		wide:   {X: 0, Y: 0, Width: 40, Height: 2},
+		left:   {X: 0, Y: 2, Width: 10, Height: 2},
+		center: {X: 15, Y: 4, Width: 10, Height: 2},
+		right:  {X: 30, Y: 15, Width: 10, Height: 3},
+		host:   {X: 0, Y: 18, Width: 20, Height: 2},
+		split:  {X: 25, Y: 18, Width: 10, Height: 2},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+	content, err := msg.Content(right)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{X: 31, Y: 16, Width: 8, Height: 1}, content)
+}
+
+func TestAlign_Cell(t *testing.T) {
+	l := bl.New("", "[grow]")
+	id := l.Cell(bl.Cell{MaxWidthPercent: 50, AlignX: bl.AlignRight})
+
+	msg := l.Resize(40, 1)
+	bounds, err := msg.Bounds(id)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{X: 20, Y: 0, Width: 20, Height: 1}, bounds)
+}
+
+func TestSizeGroup(t *testing.T) {
+	l := bl.New()
+	ok := l.Add("sgx buttons, width 4")
+	l.Add("")
+	l.Add("growx, wrap")
+	l.Add("")
+	cancel := l.Add("sgx buttons, width 8")
+	l.Add("growx")
+
+	// both buttons are as wide as the widest, even though they are in different columns.
+	msg := l.Resize(40, 2)
+	okSize, err := msg.Size(ok)
+	require.NoError(t, err)
+	cancelSize, err := msg.Size(cancel)
+	require.NoError(t, err)
+	assert.Equal(t, 8, okSize.Width)
+	assert.Equal(t, 8, cancelSize.Width)
+}
+
+func TestSizeGroup_Max(t *testing.T) {
+	l := bl.New()
+	ok := l.Add("sgx buttons, width 2:2:4")
+	l.Add("growx, wrap")
+	l.Add("")
+	cancel := l.Add("sgx buttons, width 8:8:8")
+
+	// the max of the group is used, so the first button is not clipped to its own max.
+	msg := l.Resize(40, 2)
+	expected := map[bl.ID]bl.Rect{
+		ok:     {X: 0, Y: 0, Width: 8, Height: 1},
+		cancel: {X: 8, Y: 1, Width: 8, Height: 1},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+}
+
+func TestDock_Corners(t *testing.T) {
+	l := bl.New()
+	content := l.Add("")
+	sidebar := l.Add("east 20!")
+	// the header is added last, but yields the corner to the sidebar.
+	header := l.Add("north 3!, corners yield")
+	status := l.Dock(bl.Dock{Cardinal: bl.SOUTH, Preferred: 1, Corners: bl.CornersOwn})
+
+	msg := l.Resize(80, 20)
+	expected := map[bl.ID]bl.Rect{
+		content: {X: 0, Y: 3, Width: 60, Height: 16},
+		sidebar: {X: 60, Y: 0, Width: 20, Height: 19},
+		header:  {X: 0, Y: 0, Width: 60, Height: 3},
+		status:  {X: 0, Y: 19, Width: 80, Height: 1},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+}
+
+func TestDock_CornersSameEdge(t *testing.T) {
+	l := bl.New()
+	content := l.Add("")
+	inner := l.Add("north 1!")
+	// the corner policy does not change the order of docks on the same edge, it only yields to the west dock.
+	outer := l.Add("north 2!, corners yield")
+	west := l.Add("west 5!")
+
+	msg := l.Resize(20, 10)
+	expected := map[bl.ID]bl.Rect{
+		outer:   {X: 5, Y: 0, Width: 15, Height: 2},
+		inner:   {X: 5, Y: 2, Width: 15, Height: 1},
+		west:    {X: 0, Y: 0, Width: 5, Height: 10},
+		content: {X: 5, Y: 3, Width: 15, Height: 7},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+}
+
+func TestDock_Grow(t *testing.T) {
+	l := bl.New()
+	l.Add("height 2!")
+	north := l.Add("north 1:2:10, grow, growprio 200")
+	south := l.Dock(bl.Dock{Cardinal: bl.SOUTH, Preferred: 1, Grow: true})
+
+	// the north dock grows to its max before the south dock grows.
+	msg := l.Resize(10, 20)
+	northSize, err := msg.Size(north)
+	require.NoError(t, err)
+	southSize, err := msg.Size(south)
+	require.NoError(t, err)
+	assert.Equal(t, 10, northSize.Height)
+	assert.Equal(t, 8, southSize.Height)
+}
+
+func TestDock_InvalidCorners(t *testing.T) {
+	l := bl.New()
+	l.Add("")
+	id := l.Dock(bl.Dock{Cardinal: bl.NORTH, Corners: "sometimes"})
+	_, err := l.TryResize(10, 10)
+	require.ErrorIs(t, err, bl.ErrDock{ID: id, Cardinal: bl.NORTH, Corners: "sometimes"})
+	require.ErrorContains(t, err, "invalid corner policy")
+}
+
+func TestComponents(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("width 4!, pad 1")
+	id2 := l.Add("hidden")
+	status := l.Add("dock south 1!")
+
+	msg := l.Resize(10, 5)
+	assert.Equal(t, []bl.ComponentSizeMsg{
+		{ID: id1, Rect: bl.Rect{X: 1, Y: 1, Width: 2, Height: 2}},
+		{ID: id2, Rect: bl.Rect{X: 4}},
+		{ID: status, Rect: bl.Rect{X: 0, Y: 4, Width: 10, Height: 1}},
+	}, msg.Components())
+	assert.Empty(t, bl.BubbleLayoutMsg{}.Components())
+}
+
+func TestDiff(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("width 4!")
+	id2 := l.Add("")
+	status := l.Add("dock south 1!")
+
+	prev := l.Resize(10, 5)
+	assert.Empty(t, prev.Diff(l.Resize(10, 5)))
+
+	// only the views which grew are changed.
+	next := l.Resize(12, 5)
+	assert.Equal(t, []bl.ID{id2, status}, prev.Diff(next))
+
+	// the views are taller and the status bar moves down.
+	next = l.Resize(10, 6)
+	assert.Equal(t, []bl.ID{id1, id2, status}, prev.Diff(next))
+
+	// every view is new compared to an empty layout.
+	assert.Equal(t, []bl.ID{id1, id2, status}, bl.BubbleLayoutMsg{}.Diff(prev))
+	assert.Equal(t, []bl.ID{id1, id2, status}, prev.Diff(bl.BubbleLayoutMsg{}))
+}
+
+func TestAdd_AfterResize(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("")
+	l.Resize(10, 10)
+
+	// the resize cache is cleared when a view is added.
+	id2 := l.Add("")
+	msg := l.Resize(10, 10)
+	size, err := msg.Size(id1)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Size{Width: 5, Height: 10}, size)
+	size, err = msg.Size(id2)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Size{Width: 5, Height: 10}, size)
+}
+
+func TestRemove(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("width 2!, wrap")
+	id2 := l.Add("")
+	id3 := l.Add("")
+	id4 := l.Add("dock south 1!")
+	id5 := l.Add("pos 0 0 1 1")
+	l.Resize(10, 10)
+
+	// the empty row is removed, the other views keep their IDs.
+	l.Remove(id1)
+	l.Remove(id3)
+	l.Remove(id4)
+	l.Remove(id5)
+	l.Remove(100)
+	msg := l.Resize(10, 10)
+	assert.Equal(t, []bl.ID{id2}, msg.Order())
+	bounds, err := msg.Bounds(id2)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{Width: 10, Height: 10}, bounds)
+
+	// removed IDs are not reused.
+	assert.Equal(t, id5+1, l.Add(""))
+}
+
+func TestRemove_Split(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("split 3, width 9!")
+	id2 := l.Add("width 3!")
+	id3 := l.Add("width 3!")
+	id4 := l.Add("")
+
+	// the second view becomes the first view of the split cell.
+	l.Remove(id1)
+	msg := l.Resize(20, 1)
+	expected := map[bl.ID]bl.Rect{
+		id2: {X: 0, Width: 3, Height: 1},
+		id3: {X: 3, Width: 3, Height: 1},
+		id4: {X: 6, Width: 14, Height: 1},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+
+	// the split cell has room for one less view.
+	l.Remove(id3)
+	id5, err := l.InsertAfter(id2, "width 4!")
+	require.NoError(t, err)
+	msg = l.Resize(20, 1)
+	bounds, err := msg.Bounds(id5)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{X: 3, Width: 4, Height: 1}, bounds)
+}
+
+func TestRemove_Nest(t *testing.T) {
+	l := bl.New()
+	child := bl.New()
+	host := l.Nest(child, "")
+	id2 := l.Add("")
+	id3 := child.Add("")
+	id4 := child.Add("")
+
+	// views of a nested layout can be removed from the parent.
+	l.Remove(id4)
+	msg := l.Resize(10, 1)
+	assert.Equal(t, []bl.ID{host, id3, id2}, msg.Order())
+
+	l.Remove(host)
+	msg = l.Resize(10, 1)
+	assert.Equal(t, []bl.ID{id2}, msg.Order())
+
+	// the removed layout keeps its views and is no longer nested, new views do not reuse the removed IDs.
+	id5 := child.Add("")
+	assert.Equal(t, id4+1, id5)
+	assert.Equal(t, []bl.ID{id3, id5}, child.Resize(10, 1).Order())
+
+	// once it is empty it can be nested again.
+	_, err := l.MaybeNest(child, "")
+	require.ErrorContains(t, err, "it must be empty")
+	child.Clear()
+	_, err = l.MaybeNest(child, "")
+	require.NoError(t, err)
+}
+
+func TestInsertAfter(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("width 2!")
+	id2 := l.Add("width 3!")
+	north := l.Add("dock north 1!")
+	pos := l.Add("pos 0 0 1 1")
+	l.Resize(10, 2)
+
+	id3, err := l.InsertAfter(id1, "width 4!")
+	require.NoError(t, err)
+	id4, err := l.InsertAfter(north, "dock north 1!")
+	require.NoError(t, err)
+	id5, err := l.InsertAfter(pos, "pos 1 1 1 1")
+	require.NoError(t, err)
+
+	msg := l.Resize(10, 2)
+	// the inserted dock is placed outside of the dock before it.
+	assert.Equal(t, []bl.ID{id4, north, id1, id3, id2, pos, id5}, msg.Order())
+	expected := map[bl.ID]bl.Rect{
+		id1:   {X: 0, Y: 2, Width: 2, Height: 0},
+		id3:   {X: 2, Y: 2, Width: 4, Height: 0},
+		id2:   {X: 6, Y: 2, Width: 3, Height: 0},
+		north: {X: 0, Y: 1, Width: 9, Height: 1},
+		id4:   {X: 0, Y: 0, Width: 9, Height: 1},
+		id5:   {X: 1, Y: 1, Width: 1, Height: 1},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+
+	_, err = l.InsertAfter(100, "")
+	require.ErrorContains(t, err, "view not registered")
+	_, err = l.InsertAfter(id1, "dock north 1!")
+	require.ErrorContains(t, err, "a dock cannot be inserted after a cell")
+	_, err = l.InsertAfter(id1, "width")
+	require.Error(t, err)
+}
+
+func TestInsertAfter_Wrap(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("")
+	id2 := l.Add("")
+
+	// the rest of the row is moved to a new row.
+	id3, err := l.InsertAfter(id1, "wrap")
+	require.NoError(t, err)
+	// a wrapped cell at the end of the last row starts a new row, like Add.
+	id4, err := l.InsertAfter(id2, "wrap")
+	require.NoError(t, err)
+	id5 := l.Add("")
+
+	msg := l.Resize(10, 9)
+	expected := map[bl.ID]bl.Rect{
+		id1: {X: 0, Y: 0, Width: 5, Height: 3},
+		id3: {X: 5, Y: 0, Width: 5, Height: 3},
+		id2: {X: 0, Y: 3, Width: 5, Height: 3},
+		id4: {X: 5, Y: 3, Width: 5, Height: 3},
+		id5: {X: 0, Y: 6, Width: 5, Height: 3},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("width 2!")
+	id2 := l.Add("")
+	south := l.Add("dock south 1!")
+	l.Resize(10, 5)
+
+	require.NoError(t, l.Update(id1, "width 4!"))
+	require.NoError(t, l.Update(south, "dock south 2!"))
+	msg := l.Resize(10, 5)
+	expected := map[bl.ID]bl.Rect{
+		id1:   {X: 0, Y: 0, Width: 4, Height: 3},
+		id2:   {X: 4, Y: 0, Width: 6, Height: 3},
+		south: {X: 0, Y: 3, Width: 10, Height: 2},
+	}
+	for id, r := range expected {
+		bounds, err := msg.Bounds(id)
+		require.NoError(t, err)
+		assert.Equal(t, r, bounds, "id %d", id)
+	}
+
+	// a hidden view stays hidden.
+	l.SetVisible(id2, false)
+	require.NoError(t, l.Update(id2, "width 6"))
+	assert.True(t, l.Resize(10, 5).Hidden(id2))
+	l.SetVisible(id2, true)
+	require.NoError(t, l.Update(id2, "width 6, hidden"))
+	assert.True(t, l.Resize(10, 5).Hidden(id2))
+
+	require.ErrorContains(t, l.Update(100, ""), "view not registered")
+	require.ErrorContains(t, l.Update(id1, "pos 0 0"), "a cell cannot become a positioned view")
+	require.Error(t, l.Update(id1, "width"))
+}
+
+func TestClear(t *testing.T) {
+	l := bl.New("insets 1")
+	child := bl.New()
+	l.Nest(child, "")
+	child.Add("")
+	l.Add("dock south 1!")
+	l.Add("pos 0 0")
+	l.Resize(10, 10)
+
+	l.Clear()
+	assert.Empty(t, l.Resize(10, 10).Order())
+	// the child is no longer nested, so it can be nested again once it is empty.
+	child.Clear()
+	_, err := bl.New().MaybeNest(child, "")
+	require.NoError(t, err)
+
+	// the layout constraints are kept.
+	id := l.Add("")
+	bounds, err := l.Resize(10, 10).Bounds(id)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{X: 1, Y: 1, Width: 8, Height: 8}, bounds)
+}
+
+func TestAddNamed(t *testing.T) {
+	l := bl.New()
+	sidebar := l.AddNamed("sidebar", "width 4!")
+	content := l.Add("id content")
+	l.AddNamed("status", "dock south 1!")
+	l.Add("")
+
+	msg := l.Resize(10, 5)
+	expected := map[string]bl.Size{
+		"sidebar": {Width: 4, Height: 4},
+		"content": {Width: 3, Height: 4},
+		"status":  {Width: 10, Height: 1},
+	}
+	for name, size := range expected {
+		actual, err := msg.SizeOf(name)
+		require.NoError(t, err)
+		assert.Equal(t, size, actual, name)
+	}
+	id, ok := msg.IDOf("content")
+	require.True(t, ok)
+	assert.Equal(t, content, id)
+	_, err := msg.SizeOf("missing")
+	require.ErrorContains(t, err, "view 'missing' not registered")
+
+	// updating a view keeps its name.
+	require.NoError(t, l.Update(sidebar, "width 2!"))
+	size, err := l.Resize(10, 5).SizeOf("sidebar")
+	require.NoError(t, err)
+	assert.Equal(t, bl.Size{Width: 2, Height: 4}, size)
+
+	_, err = l.MaybeAddNamed("", "")
+	require.ErrorContains(t, err, "the name is empty")
+	_, err = l.MaybeAddNamed("a", "id b")
+	require.ErrorContains(t, err, "it is named both 'a' and 'b'")
+}
+
+func TestAddNamed_Duplicate(t *testing.T) {
+	l := bl.New()
+	id1 := l.AddNamed("a", "")
+	l.Resize(10, 10)
+	id2 := l.Add("pos 0 0, id a")
+
+	_, err := l.TryResize(10, 10)
+	require.ErrorIs(t, err, bl.ErrDuplicateName{Name: "a", First: id1, Second: id2})
+	require.ErrorContains(t, err, "duplicate name 'a' for views 1 and 2")
+
+	// the name can be used again once the view is removed.
+	l.Remove(id1)
+	require.NoError(t, l.Validate())
+}
+
+func TestAddNamed_Nest(t *testing.T) {
+	l := bl.New()
+	child := bl.New()
+	host := l.Nest(child, "id host")
+	id2 := child.AddNamed("button", "")
+
+	msg := l.Resize(10, 2)
+	id, ok := msg.IDOf("button")
+	require.True(t, ok)
+	assert.Equal(t, id2, id)
+	id, ok = msg.IDOf("host")
+	require.True(t, ok)
+	assert.Equal(t, host, id)
+
+	// names must be unique across nested layouts.
+	id3 := child.AddNamed("host", "")
+	_, err := l.TryResize(10, 2)
+	require.ErrorIs(t, err, bl.ErrDuplicateName{Name: "host", First: host, Second: id3})
+}
+
+func TestMaybeNew(t *testing.T) {
+	_, err := bl.MaybeNew("flowy, rtl")
+	require.NoError(t, err)
+	_, err = bl.MaybeNew("sideways")
+	require.ErrorContains(t, err, "unknown layout constraint")
+	_, err = bl.MaybeNew("", "[10][grow]", "[3!]")
+	require.NoError(t, err)
+	_, err = bl.MaybeNew("", "[10")
+	require.ErrorContains(t, err, "missing closing bracket")
+	_, err = bl.MaybeNew("", "", "", "")
+	require.ErrorContains(t, err, "too many layout constraints")
+	require.Panics(t, func() { bl.New("sideways") })
+}
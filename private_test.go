@@ -293,6 +293,176 @@ func TestPreferenceGroup(t *testing.T) {
 			},
 			allocated: 80,
 			expected:  []int{25, 55},
+		}, {
+			name: "higher shrink priority shrinks first",
+			pg: PreferenceGroup{
+				{Preferred: 30, ShrinkPriority: 200},
+				{Preferred: 60},
+			},
+			allocated: 70,
+			expected:  []int{10, 60},
+		}, {
+			name: "shrink priority shrinks to min before others shrink",
+			pg: PreferenceGroup{
+				{Min: 10, Preferred: 30, ShrinkPriority: 200},
+				{Min: 10, Preferred: 30},
+			},
+			allocated: 30,
+			expected:  []int{10, 20},
+		}, {
+			name: "shrink priority minimums allocated first",
+			pg: PreferenceGroup{
+				{Min: 20, ShrinkPriority: 200},
+				{Min: 20},
+			},
+			allocated: 30,
+			expected:  []int{10, 20},
+		}, {
+			name: "shrink weights 1:3",
+			pg: PreferenceGroup{
+				{Preferred: 40, ShrinkWeight: 100},
+				{Preferred: 40, ShrinkWeight: 300},
+			},
+			allocated: 60,
+			expected:  []int{35, 25},
+		}, {
+			name: "shrink weights stop at min",
+			pg: PreferenceGroup{
+				{Preferred: 40, ShrinkWeight: 100},
+				{Min: 30, Preferred: 40, ShrinkWeight: 300},
+			},
+			allocated: 60,
+			expected:  []int{30, 30},
+		}, {
+			name: "shrink weights with remainder",
+			pg: PreferenceGroup{
+				{Preferred: 40},
+				{Preferred: 40},
+				{Preferred: 40},
+			},
+			allocated: 100,
+			expected:  []int{34, 33, 33},
+		}, {
+			name: "no shrink",
+			pg: PreferenceGroup{
+				{Preferred: 30, ShrinkWeight: NoShrink},
+				{Preferred: 30},
+			},
+			allocated: 40,
+			expected:  []int{30, 10},
+		}, {
+			name: "no shrink uses max without preferred",
+			pg: PreferenceGroup{
+				{Max: 30, ShrinkWeight: NoShrink},
+				{Preferred: 30},
+			},
+			allocated: 40,
+			expected:  []int{30, 10},
+		}, {
+			name: "negative allocation",
+			pg: PreferenceGroup{
+				{Min: 10},
+			},
+			allocated: -1,
+			expected:  []int{0},
+		}, {
+			name: "grow weights 2:1",
+			pg: PreferenceGroup{
+				{Grow: true, GrowWeight: 200},
+				{Grow: true, GrowWeight: 100},
+			},
+			allocated: 90,
+			expected:  []int{60, 30},
+		}, {
+			name: "grow weights are applied to the extra space",
+			pg: PreferenceGroup{
+				{Preferred: 10, Grow: true, GrowWeight: 300},
+				{Preferred: 10, Grow: true},
+			},
+			allocated: 60,
+			expected:  []int{40, 20},
+		}, {
+			name: "grow weights with max",
+			pg: PreferenceGroup{
+				{Max: 20, Grow: true, GrowWeight: 300},
+				{Grow: true},
+			},
+			allocated: 80,
+			expected:  []int{20, 60},
+		}, {
+			name: "grow weights remainder",
+			pg: PreferenceGroup{
+				{Grow: true, GrowWeight: 100},
+				{Grow: true, GrowWeight: 100},
+				{Grow: true, GrowWeight: 100},
+			},
+			allocated: 11,
+			expected:  []int{4, 4, 3},
+		}, {
+			name: "grow priority absorbs surplus first",
+			pg: PreferenceGroup{
+				{Preferred: 10, Grow: true},
+				{Preferred: 10, Grow: true, GrowPriority: 200},
+			},
+			allocated: 80,
+			expected:  []int{10, 70},
+		}, {
+			name: "grow priority grows to max before others",
+			pg: PreferenceGroup{
+				{Preferred: 10, Grow: true},
+				{Preferred: 10, Max: 30, Grow: true, GrowPriority: 200},
+			},
+			allocated: 80,
+			expected:  []int{50, 30},
+		}, {
+			name: "preferred percent",
+			pg: PreferenceGroup{
+				{PreferredPercent: 25},
+				{Grow: true},
+			},
+			allocated: 80,
+			expected:  []int{20, 60},
+		}, {
+			name: "min percent and absolute max",
+			pg: PreferenceGroup{
+				{MinPercent: 50, Max: 80},
+				{},
+			},
+			allocated: 100,
+			expected:  []int{80, 20},
+		}, {
+			name: "min percent larger than absolute max",
+			pg: PreferenceGroup{
+				{MinPercent: 50, Max: 80},
+				{},
+			},
+			allocated: 300,
+			expected:  []int{150, 150},
+		}, {
+			name: "max percent",
+			pg: PreferenceGroup{
+				{MaxPercent: 10, Grow: true},
+				{Preferred: 10},
+			},
+			allocated: 80,
+			expected:  []int{8, 10},
+		}, {
+			name: "mixed percent and absolute uses the larger preference",
+			pg: PreferenceGroup{
+				{Preferred: 30, PreferredPercent: 20},
+				{Grow: true},
+			},
+			allocated: 100,
+			expected:  []int{30, 70},
+		}, {
+			name: "fractions",
+			pg: PreferenceGroup{
+				{Preferred: 20},
+				{Grow: true, GrowWeight: 200},
+				{Grow: true, GrowWeight: 100},
+			},
+			allocated: 80,
+			expected:  []int{20, 40, 20},
 		},
 	}
 
@@ -392,6 +562,22 @@ func TestExpandSpans(t *testing.T) {
 				{{id: 1, Cell: Cell{SpanHeight: 2}}},
 				{{id: 1, Cell: Cell{SpanHeight: 2, hDuplicate: true}}},
 			},
+		}, {
+			name: "vertical span and wrap",
+			inputLayout: [][]layout{
+				{{id: 1, Cell: Cell{SpanHeight: 2}, wrap: true}},
+				{{id: 2}, {id: 3}},
+			},
+			inputString: func(bl *bubbleLayout) Grid {
+				bl.Add("spanh 2, wrap")
+				bl.Add("")
+				bl.Add("")
+				return bl.layouts
+			},
+			expected: [][]layout{
+				{{id: 1, Cell: Cell{SpanHeight: 2}, wrap: true}, {id: 0}, {id: 0}},
+				{{id: 1, Cell: Cell{SpanHeight: 2, hDuplicate: true}, wrap: true}, {id: 2}, {id: 3}},
+			},
 		}, {
 			name: "test empty space",
 			inputLayout: [][]layout{
@@ -529,7 +715,8 @@ func TestExpandSpans(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			result1 := expandSpans(tc.inputLayout)
+			result1, err := expandSpans(tc.inputLayout)
+			require.NoError(t, err)
 			assert.Len(t, result1, len(tc.expected), "number of rows mismatch")
 			for i, row := range result1 {
 				assert.Len(t, row, len(tc.expected[i]), "row %d: number of columns mismatch", i)
@@ -660,6 +847,18 @@ func TestMergeDocks(t *testing.T) {
 				{{id: 0}, {id: 2, Cell: Cell{SpanHeight: 2, MinWidth: 10, PreferredWidth: 10, MaxWidth: 10}}},
 				{{id: 1}, {id: 2, Cell: Cell{SpanHeight: 2, MinWidth: 10, PreferredWidth: 10, MaxWidth: 10, hDuplicate: true}}},
 			},
+		}, {
+			name: "border reserves space",
+			start: [][]layout{
+				{{id: 0}},
+			},
+			docks: []layout{
+				{id: 1, Dock: Dock{Cardinal: NORTH, Preferred: 5, Border: BorderRounded}},
+			},
+			expected: [][]layout{
+				{{id: 1, Cell: Cell{SpanWidth: 1, MinHeight: 2, PreferredHeight: 5, Border: BorderRounded}}},
+				{{id: 0}},
+			},
 		},
 	}
 
@@ -715,6 +914,34 @@ func TestDistillPrefs(t *testing.T) {
 			},
 			hExpected: []BoundSize{{}, {}, {}},
 			wExpected: []BoundSize{{Min: 10, Preferred: 25, Max: 50}},
+		}, {
+			name: "3x1 shrink uses the most reluctant cell",
+			input: [][]layout{
+				{{id: 1, Cell: Cell{ShrinkWidthWeight: 300, ShrinkWidthPriority: 200}}},
+				{{id: 2, Cell: Cell{ShrinkWidthWeight: 50, ShrinkWidthPriority: 150}}},
+				{{id: 3}},
+			},
+			hExpected: []BoundSize{{}, {}, {}},
+			wExpected: []BoundSize{{ShrinkWeight: 50, ShrinkPriority: 150}},
+		}, {
+			name: "1x2 no shrink",
+			input: [][]layout{
+				{
+					{id: 1, Cell: Cell{ShrinkHeightWeight: 300}},
+					{id: 2, Cell: Cell{ShrinkHeightWeight: NoShrink}},
+				},
+			},
+			hExpected: []BoundSize{{ShrinkWeight: NoShrink}},
+			wExpected: []BoundSize{{}, {}},
+		}, {
+			name: "3x1 grow uses the most eager cell",
+			input: [][]layout{
+				{{id: 1, Cell: Cell{GrowWidth: true, GrowWidthWeight: 300, GrowWidthPriority: 150}}},
+				{{id: 2, Cell: Cell{GrowWidthWeight: 50, GrowWidthPriority: 200}}},
+				{{id: 3}},
+			},
+			hExpected: []BoundSize{{}, {}, {}},
+			wExpected: []BoundSize{{Grow: true, GrowWeight: 300, GrowPriority: 200}},
 		},
 	}
 
@@ -737,7 +964,7 @@ func TestDistillPrefs_Empty(t *testing.T) {
 
 func TestDock(t *testing.T) {
 	l := New()
-	l.Dock(Dock{NORTH, 1, 2, 3})
+	l.Dock(Dock{Cardinal: NORTH, Min: 1, Preferred: 2, Max: 3})
 	bl := l.(*bubbleLayout)
 	require.Equal(t, []layout{{id: 1, Dock: Dock{Cardinal: NORTH, Min: 1, Preferred: 2, Max: 3}}}, bl.docks)
 }
@@ -762,12 +989,12 @@ func TestTooManayConstraints(t *testing.T) {
 	h := PreferenceGroup{{}}
 	w := PreferenceGroup{{}}
 	l := NewWithConstraints(w, h)
-	require.ErrorContains(t, l.Validate(), "width preferences do not match the cell height")
+	require.ErrorContains(t, l.Validate(), "width preferences do not match the number of columns")
 
 	// 0x1 layout with 1x2 constraint
 	h = append(h, BoundSize{})
 	l = NewWithConstraints(w, h)
-	require.ErrorContains(t, l.Validate(), "height preferences do not match the cell height")
+	require.ErrorContains(t, l.Validate(), "height preferences do not match the number of rows")
 }
 
 func TestProvideConstraints(t *testing.T) {
@@ -797,3 +1024,205 @@ func TestConstraintExtension(t *testing.T) {
 	require.Equal(t, append(col, addedBound), bl.hPref)
 	require.Equal(t, append(row, addedBound), bl.wPref)
 }
+
+func TestReserveInsets(t *testing.T) {
+	input := Grid{
+		{
+			{id: 1},
+			{id: 2, Cell: Cell{Padding: Insets{Top: 1, Left: 2, Bottom: 1, Right: 2}}},
+			{id: 3, Cell: Cell{MinWidth: 10, PreferredWidth: 20, MaxWidth: 30, Padding: Insets{Left: 1, Right: 1}, Margin: Insets{Left: 2, Right: 3}}},
+			{id: 4, Cell: Cell{PreferredHeight: 5, Margin: Insets{Top: 1}}},
+			{id: 5, Cell: Cell{Border: BorderNormal, Padding: Insets{Left: 1}}},
+		},
+	}
+	expected := Grid{
+		{
+			{id: 1},
+			{id: 2, Cell: Cell{MinWidth: 4, MinHeight: 2, Padding: Insets{Top: 1, Left: 2, Bottom: 1, Right: 2}}},
+			{id: 3, Cell: Cell{MinWidth: 15, PreferredWidth: 25, MaxWidth: 35, Padding: Insets{Left: 1, Right: 1}, Margin: Insets{Left: 2, Right: 3}}},
+			{id: 4, Cell: Cell{MinHeight: 1, PreferredHeight: 6, Margin: Insets{Top: 1}}},
+			{id: 5, Cell: Cell{MinWidth: 3, MinHeight: 2, Border: BorderNormal, Padding: Insets{Left: 1}}},
+		},
+	}
+	assert.Equal(t, expected, reserveInsets(input))
+	// the input is not modified.
+	assert.Equal(t, 0, input[0][1].MinWidth)
+}
+
+func TestCollapsedRowsAndCols(t *testing.T) {
+	hidden := layout{id: 2, hidden: true, hideMode: HideModeCollapse}
+	reserved := layout{id: 3, hidden: true}
+	rows, cols := collapsedRowsAndCols(Grid{
+		{{id: 1}, hidden, {id: 0}},
+		{hidden, hidden, {id: 0}},
+		{reserved, hidden, {id: 4}},
+	})
+	assert.Equal(t, []bool{false, true, false}, rows)
+	assert.Equal(t, []bool{false, true, false}, cols)
+
+	rows, cols = collapsedRowsAndCols(nil)
+	assert.Nil(t, rows)
+	assert.Nil(t, cols)
+}
+
+func TestComputeVisibleDims(t *testing.T) {
+	pg := PreferenceGroup{{Preferred: 10}, {Min: 5}, {}}
+	assert.Equal(t, []int{10, 0, 70}, pg.computeVisibleDims(80, []bool{false, true, false}))
+	assert.Equal(t, pg.computeDims(80), pg.computeVisibleDims(80, nil))
+	assert.Equal(t, []int{0, 0, 0}, pg.computeVisibleDims(80, []bool{true, true, true}))
+}
+
+func TestHideLayouts(t *testing.T) {
+	input := Grid{{
+		{id: 1, wrap: true, hidden: true, hideMode: HideModeCollapse, Cell: Cell{SpanWidth: 2, MinWidth: 10, GrowWidth: true, Border: BorderNormal}},
+		{id: 2, hidden: true, Cell: Cell{MinWidth: 10}},
+	}}
+	expected := Grid{{
+		{id: 1, wrap: true, hidden: true, hideMode: HideModeCollapse, Cell: Cell{SpanWidth: 2}},
+		{id: 2, hidden: true, Cell: Cell{MinWidth: 10}},
+	}}
+	assert.Equal(t, expected, hideLayouts(input))
+}
+
+func TestSplitAvailable(t *testing.T) {
+	assert.False(t, splitAvailable(nil))
+	assert.False(t, splitAvailable([]layout{{id: 1}}))
+	assert.True(t, splitAvailable([]layout{{id: 1, Cell: Cell{Split: 2}}}))
+	assert.True(t, splitAvailable([]layout{{id: 1, Cell: Cell{Split: 3}}, {id: 2, joinSplit: true}}))
+	assert.False(t, splitAvailable([]layout{{id: 1, Cell: Cell{Split: 2}}, {id: 2, joinSplit: true}}))
+}
+
+func TestMergeSplits(t *testing.T) {
+	host := layout{id: 1, Cell: Cell{Split: 3, MinWidth: 5, PreferredWidth: 10, MaxWidth: 20, MinHeight: 1}}
+	second := layout{id: 2, joinSplit: true, Cell: Cell{MinWidth: 5, MaxWidth: 10, MinHeight: 3, GrowWidth: true}}
+	third := layout{id: 3, joinSplit: true, hidden: true, hideMode: HideModeCollapse, Cell: Cell{MinWidth: 50}}
+	input := Grid{{host, second, third, {id: 4}}}
+
+	expected := Grid{{
+		{
+			id:    1,
+			split: []layout{host, second, third},
+			// the collapsed view is not included, and the second view has no preferred width so neither does the cell.
+			Cell: Cell{Split: 3, MinWidth: 10, MaxWidth: 30, MinHeight: 3, GrowWidth: true},
+		},
+		{id: 4},
+	}}
+	assert.Equal(t, expected, mergeSplits(input))
+
+	// every view has a preferred width.
+	second.PreferredWidth = 7
+	input = Grid{{host, second, third}}
+	assert.Equal(t, 17, mergeSplits(input)[0][0].PreferredWidth)
+}
+
+func TestSplitCell_AllMax(t *testing.T) {
+	c := splitCell([]layout{
+		{Cell: Cell{MaxWidth: 10, MaxHeight: 2}},
+		{Cell: Cell{MaxWidth: 20, MaxHeight: 3}},
+	})
+	assert.Equal(t, Cell{MaxWidth: 30, MaxHeight: 3}, c)
+}
+
+func TestMinimums(t *testing.T) {
+	pg := PreferenceGroup{
+		{Min: 10},
+		{MinPercent: 50},
+		{Preferred: 20, ShrinkWeight: NoShrink},
+		{Preferred: 20},
+	}
+	assert.Equal(t, []int{10, 40, 20, 0}, pg.minimums(80))
+}
+
+func TestTranspose(t *testing.T) {
+	c := Cell{
+		SpanWidth: 1, MinWidth: 2, PreferredWidth: 3, MaxWidth: 4, MinWidthPercent: 5, GrowWidth: true, GrowWidthWeight: 6,
+		ShrinkHeightPriority: 7, Padding: Insets{Top: 1, Left: 2, Bottom: 3, Right: 4}, wDuplicate: true,
+	}
+	expected := Cell{
+		SpanHeight: 1, MinHeight: 2, PreferredHeight: 3, MaxHeight: 4, MinHeightPercent: 5, GrowHeight: true, GrowHeightWeight: 6,
+		ShrinkWidthPriority: 7, Padding: Insets{Top: 2, Left: 1, Bottom: 4, Right: 3}, hDuplicate: true,
+	}
+	assert.Equal(t, expected, c.transpose())
+	assert.Equal(t, c, c.transpose().transpose())
+
+	l := layout{id: 1, split: []layout{{id: 1, Cell: c}, {id: 2}}}
+	assert.Equal(t, layout{id: 1, splitVertical: true, split: []layout{{id: 1, Cell: expected}, {id: 2}}}, l.transpose())
+}
+
+func TestTransposeGrid(t *testing.T) {
+	input := Grid{
+		{{id: 1}, {id: 2, Cell: Cell{MinWidth: 3}}},
+		{{id: 3}, {id: 4}},
+	}
+	expected := Grid{
+		{{id: 1}, {id: 3}},
+		{{id: 2, Cell: Cell{MinHeight: 3}}, {id: 4}},
+	}
+	assert.Equal(t, expected, transposeGrid(input))
+	assert.Nil(t, transposeGrid(nil))
+}
+
+func TestMirrorGrid(t *testing.T) {
+	input := Grid{
+		{{id: 1, Cell: Cell{SpanWidth: 2}}, {id: 1, Cell: Cell{SpanWidth: 2, wDuplicate: true}}, {id: 2, Cell: Cell{Margin: Insets{Left: 1}}}},
+		{{id: 0}, {id: 0}, {id: 3, split: []layout{{id: 3}, {id: 4}}}},
+	}
+	expected := Grid{
+		{{id: 2, Cell: Cell{Margin: Insets{Right: 1}}}, {id: 1, Cell: Cell{SpanWidth: 2}}, {id: 1, Cell: Cell{SpanWidth: 2, wDuplicate: true}}},
+		{{id: 3, split: []layout{{id: 4}, {id: 3}}}, {id: 0}, {id: 0}},
+	}
+	assert.Equal(t, expected, mirrorGrid(input))
+}
+
+func TestPosRect(t *testing.T) {
+	testcases := []struct {
+		name     string
+		pos      Pos
+		expected Rect
+	}{
+		{name: "offset", pos: Pos{X: 2, Y: 3, Width: 10, Height: 5}, expected: Rect{X: 2, Y: 3, Width: 10, Height: 5}},
+		{name: "centered", pos: Pos{XPercent: 50, YPercent: 50, Width: 40, Height: 10}, expected: Rect{X: 30, Y: 15, Width: 40, Height: 10}},
+		{name: "bottom right", pos: Pos{X: -1, XPercent: 100, YPercent: 100, Width: 30, Height: 3}, expected: Rect{X: 69, Y: 37, Width: 30, Height: 3}},
+		{name: "percent size", pos: Pos{XPercent: 50, YPercent: 50, WidthPercent: 50, HeightPercent: 50}, expected: Rect{X: 25, Y: 10, Width: 50, Height: 20}},
+		{name: "extends to the edge", pos: Pos{X: 10, Y: 5}, expected: Rect{X: 10, Y: 5, Width: 90, Height: 35}},
+		{name: "clamped", pos: Pos{X: 90, Y: -5, Width: 200, Height: 10}, expected: Rect{X: 0, Y: 0, Width: 100, Height: 10}},
+		{name: "clamped offset", pos: Pos{X: 95, Y: 38, Width: 10, Height: 5}, expected: Rect{X: 90, Y: 35, Width: 10, Height: 5}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, tc.pos.rect(100, 40))
+		})
+	}
+}
+
+func TestReconcileSizeGroups(t *testing.T) {
+	g := Grid{
+		{
+			{id: 1, Cell: Cell{SizeGroup: "buttons", MinWidth: 5, PreferredWidth: 8, MaxWidth: 20, PreferredHeight: 1}},
+			{id: 2, Cell: Cell{SizeGroupWidth: "buttons", PreferredWidth: 12, MaxWidth: 15}},
+		},
+		{
+			{id: 3, Cell: Cell{SizeGroup: "buttons", PreferredWidth: 10, PreferredHeight: 3}},
+			{id: 4, Cell: Cell{SizeGroup: "buttons", PreferredWidth: 30}, hidden: true, hideMode: HideModeCollapse},
+			{id: 5, Cell: Cell{SizeGroup: "other", PreferredWidth: 7}},
+		},
+	}
+
+	ret := reconcileSizeGroups(g)
+	// the width is shared by 1, 2 and 3. There is no max because 3 does not have one.
+	for _, l := range []layout{ret[0][0], ret[0][1], ret[1][0]} {
+		assert.Equal(t, BoundSize{Min: 5, Preferred: 12}, l.widthBound(), "id %d", l.id)
+	}
+	// 1 and 3 share the height, 2 is only in the width group.
+	assert.Equal(t, 3, ret[0][0].PreferredHeight)
+	assert.Equal(t, 0, ret[0][1].PreferredHeight)
+	assert.Equal(t, 3, ret[1][0].PreferredHeight)
+	// collapsed views are not part of their group.
+	assert.Equal(t, 30, ret[1][1].PreferredWidth)
+	assert.Equal(t, 7, ret[1][2].PreferredWidth)
+	// the input is not modified.
+	assert.Equal(t, 8, g[0][0].PreferredWidth)
+}
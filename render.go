@@ -0,0 +1,329 @@
+package bubblelayout
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Render composites views into the positions allocated by a BubbleLayoutMsg.
+// Each view is clipped or padded to the content area of its ID, views that
+// are missing from the map are left blank. ANSI styles are preserved and wide
+// characters are measured by their display width.
+//
+// Borders are drawn for every view that has one, even if it is missing from the
+// map. Borders which touch are merged into junctions. Views are drawn in the
+// stacking order, views which are positioned or on a higher layer than the
+// bottom layer cover the views beneath them instead of merging borders.
+//
+// The result is a string which is exactly as wide and tall as the layout.
+func Render(msg BubbleLayoutMsg, views map[ID]string) string {
+	c := newCanvas(msg.width, msg.height)
+	var base int
+	if len(msg.order) > 0 {
+		base = msg.views[msg.order[0]].layer
+	}
+	for _, id := range msg.order {
+		p := msg.views[id]
+		if p.floating || p.layer > base {
+			// views which are on top hide everything beneath them, including borders.
+			c.clear(p.frame)
+		}
+		if p.border != BorderNone {
+			c.drawBorder(p.frame, p.border)
+		}
+		view, ok := views[id]
+		if !ok {
+			continue
+		}
+		c.draw(p.content, view)
+	}
+	return c.String()
+}
+
+// canvasCell is a single terminal cell.
+type canvasCell struct {
+	// style is the SGR escape sequences active for this cell.
+	style string
+	// char is the text drawn in this cell. It is empty for the trailing half of a wide character.
+	char string
+}
+
+var blankCell = canvasCell{char: " "}
+
+// canvas is a grid of cells which views are drawn on to.
+type canvas struct {
+	width  int
+	height int
+	cells  [][]canvasCell
+	// borders holds the border directions drawn in each cell, so that overlapping borders can be merged.
+	borders [][]uint8
+}
+
+func newCanvas(width, height int) *canvas {
+	c := &canvas{
+		width:  max(width, 0),
+		height: max(height, 0),
+	}
+	c.cells = make([][]canvasCell, c.height)
+	c.borders = make([][]uint8, c.height)
+	for y := range c.cells {
+		c.cells[y] = make([]canvasCell, c.width)
+		c.borders[y] = make([]uint8, c.width)
+		for x := range c.cells[y] {
+			c.cells[y][x] = blankCell
+		}
+	}
+	return c
+}
+
+// set writes a cell, cleaning up any wide characters that are partially overwritten.
+func (c *canvas) set(x, y int, cell canvasCell) {
+	if x < 0 || y < 0 || x >= c.width || y >= c.height {
+		return
+	}
+	row := c.cells[y]
+	if cell.char != "" && row[x].char == "" && x > 0 {
+		row[x-1] = blankCell
+	}
+	if x+1 < c.width && row[x+1].char == "" {
+		row[x+1] = blankCell
+	}
+	row[x] = cell
+	c.borders[y][x] = 0
+}
+
+// clear blanks the cells in a rectangle.
+func (c *canvas) clear(r Rect) {
+	for y := r.Y; y < r.Y+r.Height; y++ {
+		for x := r.X; x < r.X+r.Width; x++ {
+			c.set(x, y, blankCell)
+		}
+	}
+}
+
+// border directions, they are combined into a mask for each cell of a border.
+const (
+	borderUp uint8 = 1 << iota
+	borderDown
+	borderLeft
+	borderRight
+)
+
+// borderGlyphs are the characters for each border style, indexed by the border mask.
+var borderGlyphs = map[BorderStyle][16]string{
+	BorderNormal:  {" ", "│", "│", "│", "─", "┘", "┐", "┤", "─", "└", "┌", "├", "─", "┴", "┬", "┼"},
+	BorderRounded: {" ", "│", "│", "│", "─", "╯", "╮", "┤", "─", "╰", "╭", "├", "─", "┴", "┬", "┼"},
+	BorderThick:   {" ", "┃", "┃", "┃", "━", "┛", "┓", "┫", "━", "┗", "┏", "┣", "━", "┻", "┳", "╋"},
+	BorderDouble:  {" ", "║", "║", "║", "═", "╝", "╗", "╣", "═", "╚", "╔", "╠", "═", "╩", "╦", "╬"},
+}
+
+// addBorder merges the border directions into a cell and draws the matching glyph.
+func (c *canvas) addBorder(x, y int, mask uint8, style BorderStyle) {
+	if x < 0 || y < 0 || x >= c.width || y >= c.height {
+		return
+	}
+	glyphs, ok := borderGlyphs[style]
+	if !ok {
+		glyphs = borderGlyphs[BorderNormal]
+	}
+	mask |= c.borders[y][x]
+	c.set(x, y, canvasCell{char: glyphs[mask]})
+	c.borders[y][x] = mask
+}
+
+// drawBorder draws a border around the edge of the rectangle.
+func (c *canvas) drawBorder(r Rect, style BorderStyle) {
+	if r.Width < 2 || r.Height < 2 {
+		return
+	}
+	left, right := r.X, r.X+r.Width-1
+	top, bottom := r.Y, r.Y+r.Height-1
+
+	c.addBorder(left, top, borderDown|borderRight, style)
+	c.addBorder(right, top, borderDown|borderLeft, style)
+	c.addBorder(left, bottom, borderUp|borderRight, style)
+	c.addBorder(right, bottom, borderUp|borderLeft, style)
+	for x := left + 1; x < right; x++ {
+		c.addBorder(x, top, borderLeft|borderRight, style)
+		c.addBorder(x, bottom, borderLeft|borderRight, style)
+	}
+	for y := top + 1; y < bottom; y++ {
+		c.addBorder(left, y, borderUp|borderDown, style)
+		c.addBorder(right, y, borderUp|borderDown, style)
+	}
+}
+
+// draw writes a view into the rectangle, clipping or padding each line to fit.
+func (c *canvas) draw(r Rect, view string) {
+	lines := strings.Split(view, "\n")
+	for y := 0; y < r.Height; y++ {
+		var line string
+		if y < len(lines) {
+			line = lines[y]
+		}
+		c.drawLine(r.X, r.Y+y, r.Width, line)
+	}
+}
+
+// drawLine writes a single line of text, clipping it to width and padding it with blank cells.
+func (c *canvas) drawLine(x, y, width int, line string) {
+	var style string
+	col := 0
+	// last is the column of the last character written, combining characters are attached to it.
+	last := -1
+	for i := 0; i < len(line) && col < width; {
+		if line[i] == '\x1b' {
+			seq, n := parseEscape(line[i:])
+			i += n
+			if strings.HasPrefix(seq, "\x1b[") && strings.HasSuffix(seq, "m") {
+				style = applySGR(style, seq)
+			}
+			continue
+		}
+
+		r, n := utf8.DecodeRuneInString(line[i:])
+		i += n
+		w := runeWidth(r)
+		switch {
+		case w == 0:
+			// attach combining characters to the previous cell, drop control characters.
+			if last >= 0 && !unicode.IsControl(r) && x+last >= 0 && x+last < c.width && y >= 0 && y < c.height {
+				c.cells[y][x+last].char += string(r)
+			}
+		case col+w > width:
+			// a wide character which does not fit is replaced with padding.
+			i = len(line)
+		default:
+			c.set(x+col, y, canvasCell{style: style, char: string(r)})
+			if w == 2 {
+				c.set(x+col+1, y, canvasCell{style: style})
+			}
+			last = col
+			col += w
+		}
+	}
+	for ; col < width; col++ {
+		c.set(x+col, y, blankCell)
+	}
+}
+
+// String renders the canvas, emitting style changes only where they are needed.
+func (c *canvas) String() string {
+	var sb strings.Builder
+	for y, row := range c.cells {
+		if y > 0 {
+			sb.WriteByte('\n')
+		}
+		var style string
+		for _, cell := range row {
+			if cell.char == "" {
+				continue
+			}
+			if cell.style != style {
+				if style != "" {
+					sb.WriteString(sgrReset)
+				}
+				sb.WriteString(cell.style)
+				style = cell.style
+			}
+			sb.WriteString(cell.char)
+		}
+		if style != "" {
+			sb.WriteString(sgrReset)
+		}
+	}
+	return sb.String()
+}
+
+const sgrReset = "\x1b[0m"
+
+// parseEscape returns the escape sequence at the start of str and its length in bytes.
+// CSI sequences end with a byte in the range 0x40-0x7E, OSC sequences end with BEL or ST.
+func parseEscape(str string) (string, int) {
+	if len(str) < 2 {
+		return str, len(str)
+	}
+	switch str[1] {
+	case '[':
+		for i := 2; i < len(str); i++ {
+			if str[i] >= 0x40 && str[i] <= 0x7e {
+				return str[:i+1], i + 1
+			}
+		}
+	case ']':
+		for i := 2; i < len(str); i++ {
+			if str[i] == '\a' {
+				return str[:i+1], i + 1
+			}
+			if str[i] == '\x1b' && i+1 < len(str) && str[i+1] == '\\' {
+				return str[:i+2], i + 2
+			}
+		}
+	default:
+		return str[:2], 2
+	}
+	return str, len(str)
+}
+
+// applySGR returns the style after applying an SGR sequence. Resets clear the existing style.
+func applySGR(style, seq string) string {
+	params := seq[2 : len(seq)-1]
+	if params == "" || params == "0" {
+		return ""
+	}
+	if strings.HasPrefix(params, "0;") {
+		return seq
+	}
+	return style + seq
+}
+
+// wideRanges are the wide (W) and full width (F) ranges from the Unicode 13.0 EastAsianWidth.txt, which
+// include the emoji that are displayed as two cells. The ranges are sorted and do not overlap.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f}, {0x231a, 0x231b}, {0x2329, 0x232a}, {0x23e9, 0x23ec},
+	{0x23f0, 0x23f0}, {0x23f3, 0x23f3}, {0x25fd, 0x25fe}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267f, 0x267f}, {0x2693, 0x2693}, {0x26a1, 0x26a1},
+	{0x26aa, 0x26ab}, {0x26bd, 0x26be}, {0x26c4, 0x26c5}, {0x26ce, 0x26ce},
+	{0x26d4, 0x26d4}, {0x26ea, 0x26ea}, {0x26f2, 0x26f3}, {0x26f5, 0x26f5},
+	{0x26fa, 0x26fa}, {0x26fd, 0x26fd}, {0x2705, 0x2705}, {0x270a, 0x270b},
+	{0x2728, 0x2728}, {0x274c, 0x274c}, {0x274e, 0x274e}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27b0, 0x27b0}, {0x27bf, 0x27bf},
+	{0x2b1b, 0x2b1c}, {0x2b50, 0x2b50}, {0x2b55, 0x2b55}, {0x2e80, 0x2e99},
+	{0x2e9b, 0x2ef3}, {0x2f00, 0x2fd5}, {0x2ff0, 0x2ffb}, {0x3000, 0x303e},
+	{0x3041, 0x3096}, {0x3099, 0x30ff}, {0x3105, 0x312f}, {0x3131, 0x318e},
+	{0x3190, 0x31e3}, {0x31f0, 0x321e}, {0x3220, 0x3247}, {0x3250, 0x4dbf},
+	{0x4e00, 0xa48c}, {0xa490, 0xa4c6}, {0xa960, 0xa97c}, {0xac00, 0xd7a3},
+	{0xf900, 0xfaff}, {0xfe10, 0xfe19}, {0xfe30, 0xfe52}, {0xfe54, 0xfe66},
+	{0xfe68, 0xfe6b}, {0xff01, 0xff60}, {0xffe0, 0xffe6}, {0x16fe0, 0x16fe4},
+	{0x16ff0, 0x16ff1}, {0x17000, 0x187f7}, {0x18800, 0x18cd5}, {0x18d00, 0x18d08},
+	{0x1b000, 0x1b11e}, {0x1b150, 0x1b152}, {0x1b164, 0x1b167}, {0x1b170, 0x1b2fb},
+	{0x1f004, 0x1f004}, {0x1f0cf, 0x1f0cf}, {0x1f18e, 0x1f18e}, {0x1f191, 0x1f19a},
+	{0x1f200, 0x1f202}, {0x1f210, 0x1f23b}, {0x1f240, 0x1f248}, {0x1f250, 0x1f251},
+	{0x1f260, 0x1f265}, {0x1f300, 0x1f320}, {0x1f32d, 0x1f335}, {0x1f337, 0x1f37c},
+	{0x1f37e, 0x1f393}, {0x1f3a0, 0x1f3ca}, {0x1f3cf, 0x1f3d3}, {0x1f3e0, 0x1f3f0},
+	{0x1f3f4, 0x1f3f4}, {0x1f3f8, 0x1f43e}, {0x1f440, 0x1f440}, {0x1f442, 0x1f4fc},
+	{0x1f4ff, 0x1f53d}, {0x1f54b, 0x1f54e}, {0x1f550, 0x1f567}, {0x1f57a, 0x1f57a},
+	{0x1f595, 0x1f596}, {0x1f5a4, 0x1f5a4}, {0x1f5fb, 0x1f64f}, {0x1f680, 0x1f6c5},
+	{0x1f6cc, 0x1f6cc}, {0x1f6d0, 0x1f6d2}, {0x1f6d5, 0x1f6d7}, {0x1f6eb, 0x1f6ec},
+	{0x1f6f4, 0x1f6fc}, {0x1f7e0, 0x1f7eb}, {0x1f90c, 0x1f93a}, {0x1f93c, 0x1f945},
+	{0x1f947, 0x1f978}, {0x1f97a, 0x1f9cb}, {0x1f9cd, 0x1f9ff}, {0x1fa70, 0x1fa74},
+	{0x1fa78, 0x1fa7a}, {0x1fa80, 0x1fa86}, {0x1fa90, 0x1faa8}, {0x1fab0, 0x1fab6},
+	{0x1fac0, 0x1fac2}, {0x1fad0, 0x1fad6}, {0x20000, 0x2fffd}, {0x30000, 0x3fffd},
+}
+
+// runeWidth returns the number of terminal cells used to display a rune.
+func runeWidth(r rune) int {
+	if unicode.IsControl(r) || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	for _, rng := range wideRanges {
+		if r < rng[0] {
+			break
+		}
+		if r <= rng[1] {
+			return 2
+		}
+	}
+	return 1
+}
@@ -0,0 +1,186 @@
+package bubblelayout_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	bl "github.com/winder/bubblelayout"
+)
+
+func TestRender(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("")
+	id2 := l.Add("wrap")
+	id3 := l.Add("span 2")
+	north := l.Add("dock north 1!")
+	west := l.Add("dock west 2!")
+
+	msg := l.Resize(10, 5)
+	out := bl.Render(msg, map[bl.ID]string{
+		id1:   "aaaaaaaaaa\naaaa\naaaa",
+		id2:   "bb",
+		id3:   "cc\ncc",
+		north: "north",
+		west:  "ww\nww\nww\nww\nww\nww",
+	})
+
+	expected := []string{
+		"wwnorth   ",
+		"wwaaaabb  ",
+		"wwaaaa    ",
+		"wwcc      ",
+		"wwcc      ",
+	}
+	assert.Equal(t, strings.Join(expected, "\n"), out)
+}
+
+func TestRender_MissingViews(t *testing.T) {
+	l := bl.New()
+	l.Add("")
+	id2 := l.Add("")
+
+	msg := l.Resize(4, 2)
+	out := bl.Render(msg, map[bl.ID]string{id2: "xx\nxx"})
+	assert.Equal(t, "  xx\n  xx", out)
+}
+
+func TestRender_Styles(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("")
+	id2 := l.Add("")
+
+	msg := l.Resize(6, 1)
+	out := bl.Render(msg, map[bl.ID]string{
+		// the style is clipped with the text and reset at the end of the view.
+		id1: "\x1b[1mbold\x1b[0m",
+		id2: "\x1b[31mr\x1b[0mx",
+	})
+	assert.Equal(t, "\x1b[1mbol\x1b[0m\x1b[31mr\x1b[0mx ", out)
+}
+
+func TestRender_WideCharacters(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("")
+	id2 := l.Add("")
+
+	msg := l.Resize(6, 1)
+	out := bl.Render(msg, map[bl.ID]string{
+		id1: "猫咪",
+		// combining characters do not use a cell.
+		id2: "éf",
+	})
+	// only one wide character fits in 3 cells, the remainder is padded.
+	assert.Equal(t, "猫 éf ", out)
+}
+
+func TestRender_WideSymbols(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("")
+	id2 := l.Add("")
+
+	msg := l.Resize(10, 1)
+	out := bl.Render(msg, map[bl.ID]string{
+		id1: "☕x🚀",
+		// symbols which are not wide use a single cell.
+		id2: "☃✓",
+	})
+	assert.Equal(t, "☕x🚀☃✓   ", out)
+}
+
+func TestRender_Padding(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("pad 1")
+	id2 := l.Add("margin 0 1")
+
+	msg := l.Resize(10, 3)
+	out := bl.Render(msg, map[bl.ID]string{
+		id1: "aaaaa\naaaaa",
+		id2: "bbbbb\nbbbbb\nbbbbb",
+	})
+
+	expected := []string{
+		"      bbb ",
+		" aaa  bbb ",
+		"      bbb ",
+	}
+	assert.Equal(t, strings.Join(expected, "\n"), out)
+}
+
+func TestRender_Borders(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("border")
+	id2 := l.Add("border, wrap")
+	id3 := l.Add("border, span 2")
+
+	msg := l.Resize(10, 6)
+	out := bl.Render(msg, map[bl.ID]string{
+		id1: "aaaaa\naaaaa",
+		id2: "bbbbb\nbbbbb",
+		id3: "ccccccccc\ncccccccccc",
+	})
+
+	// the shared edges are merged into junctions, the views on the right and
+	// bottom gain the space that would have been used by their own border.
+	expected := []string{
+		"┌───┬────┐",
+		"│aaa│bbbb│",
+		"├───┴────┤",
+		"│cccccccc│",
+		"│cccccccc│",
+		"└────────┘",
+	}
+	assert.Equal(t, strings.Join(expected, "\n"), out)
+
+	content, err := msg.Content(id2)
+	require.NoError(t, err)
+	assert.Equal(t, bl.Rect{X: 5, Y: 1, Width: 4, Height: 1}, content)
+}
+
+func TestRender_BorderStyles(t *testing.T) {
+	l := bl.New()
+	l.Add("border rounded, width 5!")
+	l.Add("border double, margin 0 1 0 0")
+	l.Add("dock south 3!, border thick")
+
+	msg := l.Resize(11, 6)
+	out := bl.Render(msg, nil)
+
+	// the dock shares an edge with both cells, but not the gap between them.
+	expected := []string{
+		"╭───╮ ╔═══╗",
+		"│   │ ║   ║",
+		"┣━━━┻━┻━━━┫",
+		"┃         ┃",
+		"┃         ┃",
+		"┗━━━━━━━━━┛",
+	}
+	assert.Equal(t, strings.Join(expected, "\n"), out)
+}
+
+func TestRender_Layers(t *testing.T) {
+	l := bl.New()
+	id1 := l.Add("border")
+	id2 := l.Add("border, layer -1")
+	popup := l.Add("pos 2 1 6 3, border")
+
+	msg := l.Resize(10, 5)
+	out := bl.Render(msg, map[bl.ID]string{
+		id1:   "aaa\naaa\naaa",
+		id2:   "bbb\nbbb\nbbb",
+		popup: "pppp",
+	})
+
+	// the second view is on a lower layer, so the first view is drawn over the shared edge.
+	// The popup covers both of them instead of merging borders.
+	expected := []string{
+		"┌───┐────┐",
+		"│a┌────┐ │",
+		"│a│pppp│ │",
+		"│a└────┘ │",
+		"└───┘────┘",
+	}
+	assert.Equal(t, strings.Join(expected, "\n"), out)
+}
@@ -7,7 +7,7 @@ import (
 	"strings"
 )
 
-var borderSizePattern = regexp.MustCompile(`^([\d]+):?([\d]+)?:?([\d]+)?(!)?$`)
+var borderSizePattern = regexp.MustCompile(`^([\d]+(?:%|fr)?):?([\d]+(?:%|fr)?)?:?([\d]+(?:%|fr)?)?(!)?$`)
 
 // getNumbers returns all numbers from the slice until a non-numeric string is reached.
 func getNumbers(str []string) []int {
@@ -22,6 +22,59 @@ func getNumbers(str []string) []int {
 	return result
 }
 
+// makeShrinkWeight converts the optional shrink weight. Without a number the default weight is used,
+// a weight of 0 means that the size should not shrink.
+func makeShrinkWeight(nums []int) (int, error) {
+	switch {
+	case len(nums) == 0:
+		return DefaultWeight, nil
+	case len(nums) > 1:
+		return 0, fmt.Errorf("wrong number of inputs, expected 0 or 1 received '%v'", nums)
+	case nums[0] < 0:
+		return 0, fmt.Errorf("shrink weight must not be negative")
+	case nums[0] == 0:
+		return NoShrink, nil
+	default:
+		return nums[0], nil
+	}
+}
+
+func isBorderStyle(str string) bool {
+	switch BorderStyle(str) {
+	case BorderNormal, BorderRounded, BorderThick, BorderDouble:
+		return true
+	default:
+		return false
+	}
+}
+
+func isCornerPolicy(str string) bool {
+	switch CornerPolicy(str) {
+	case CornersByOrder, CornersOwn, CornersYield:
+		return true
+	default:
+		return false
+	}
+}
+
+func isAlignX(str string) bool {
+	switch Alignment(str) {
+	case AlignLeft, AlignCenter, AlignRight:
+		return true
+	default:
+		return false
+	}
+}
+
+func isAlignY(str string) bool {
+	switch Alignment(str) {
+	case AlignTop, AlignCenter, AlignBottom:
+		return true
+	default:
+		return false
+	}
+}
+
 func isCardinal(str string) bool {
 	switch Cardinal(str) {
 	case NORTH, SOUTH, EAST, WEST:
@@ -31,12 +84,91 @@ func isCardinal(str string) bool {
 	}
 }
 
+// makeInsets converts 1, 2 or 4 numbers into Insets.
+// One number is used for all sides, two numbers are the vertical and horizontal insets
+// and four numbers are the top, left, bottom and right insets.
+func makeInsets(nums []int) (Insets, error) {
+	for _, n := range nums {
+		if n < 0 {
+			return Insets{}, fmt.Errorf("insets must not be negative, received '%v'", nums)
+		}
+	}
+	switch len(nums) {
+	case 1:
+		return Insets{Top: nums[0], Left: nums[0], Bottom: nums[0], Right: nums[0]}, nil
+	case 2:
+		return Insets{Top: nums[0], Left: nums[1], Bottom: nums[0], Right: nums[1]}, nil
+	case 4:
+		return Insets{Top: nums[0], Left: nums[1], Bottom: nums[2], Right: nums[3]}, nil
+	default:
+		return Insets{}, fmt.Errorf("wrong number of insets, expected 1, 2 or 4 received '%v'", nums)
+	}
+}
+
+// parsePos parses the coordinates and optional size of a positioned view, E.g. "center center 40 10".
+//
+// A coordinate is a number of cells from the left or top edge. A negative number is the distance from the right
+// or bottom edge to the far side of the view, so "-0" is against the edge. A percentage positions the view within
+// the layout, "0%" is the left or top edge and "100%" is the right or bottom edge. "center" is the same as "50%".
+// A size is a number of cells or a percentage of the layout.
+func parsePos(parts []string) (Pos, error) {
+	var result Pos
+	var err error
+	if result.X, result.XPercent, err = parseCoordinate(parts[0]); err != nil {
+		return Pos{}, err
+	}
+	if result.Y, result.YPercent, err = parseCoordinate(parts[1]); err != nil {
+		return Pos{}, err
+	}
+	if len(parts) < 4 {
+		return result, nil
+	}
+	if result.Width, result.WidthPercent, err = parseLength(parts[2]); err != nil {
+		return Pos{}, err
+	}
+	if result.Height, result.HeightPercent, err = parseLength(parts[3]); err != nil {
+		return Pos{}, err
+	}
+	return result, nil
+}
+
+// parseCoordinate returns the offset and percentage of a coordinate, see parsePos.
+func parseCoordinate(str string) (int, int, error) {
+	if str == "center" {
+		return 0, 50, nil
+	}
+	if strings.HasPrefix(str, "-") {
+		num, err := strconv.Atoi(str[1:])
+		if err != nil || num < 0 {
+			return 0, 0, fmt.Errorf("invalid coordinate '%s'", str)
+		}
+		return -num, 100, nil
+	}
+	offset, percent, err := parseLength(str)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid coordinate '%s'", str)
+	}
+	return offset, percent, nil
+}
+
+// parseLength returns the number of cells or the percentage in a length, E.g. "10" or "50%".
+func parseLength(str string) (int, int, error) {
+	values, err := getSizeValues([]string{str})
+	if err != nil || len(values) != 1 || values[0].unit == unitFraction || values[0].value < 0 {
+		return 0, 0, fmt.Errorf("invalid length '%s'", str)
+	}
+	return values[0].cells(), values[0].percent(), nil
+}
+
 // parseSize parses the BoundSize string.
 // The format is "min:preferred:max", however there are shorter versions since for instance it is seldom needed to specify the maximum size.
 //
 // A single value (E.g. "10") sets only the preferred size and is exactly the same as "null:10:null" and ":10:" and "n:10:n".
 // Two values (E.g. "10:20") means minimum and preferred size and is exactly the same as "10:20:null" and "10:20:" and "10:20:n"
 // The use a of an exclamation mark (E.g. "20!") means that the value should be used for all size types and no colon may then be used in the string. It is the same as "20:20:20".
+//
+// Values may be a percentage of the available space (E.g. "30%" or "50%:n:80"). A fraction (E.g. "1fr") may be
+// used as the preferred size, it grows with a weight proportional to the fraction.
 func parseSize(sz string) (BoundSize, error) {
 	// normalize the inputLayout for some of the weirder options
 	sz = strings.ReplaceAll(sz, "null", "0")
@@ -56,27 +188,99 @@ func parseSize(sz string) (BoundSize, error) {
 		return BoundSize{}, fmt.Errorf("invalid bound size '%s': did not match pattern", sz)
 	}
 
-	nums := getNumbers(parts[1:])
+	values, err := getSizeValues(parts[1:4])
+	if err != nil {
+		return BoundSize{}, fmt.Errorf("invalid bound size '%s': %w", sz, err)
+	}
 	exp := parts[4] == "!"
 
-	if exp && len(nums) != 1 {
+	if exp && len(values) != 1 {
 		return BoundSize{}, fmt.Errorf("invalid bound size '%s': use '!' with only one number", sz)
 	}
 
-	if exp {
-		return BoundSize{Min: nums[0], Preferred: nums[0], Max: nums[0]}, nil
+	var result BoundSize
+	switch {
+	case exp:
+		if values[0].unit == unitFraction {
+			return BoundSize{}, fmt.Errorf("invalid bound size '%s': use '!' with cells or percentages", sz)
+		}
+		values = []sizeValue{values[0], values[0], values[0]}
+	case len(values) == 1:
+		// a single value is the preferred size.
+		values = []sizeValue{{}, values[0]}
 	}
 
-	if len(nums) == 1 {
-		return BoundSize{Preferred: nums[0]}, nil
+	for i, v := range values {
+		if v.unit == unitFraction && i != 1 {
+			return BoundSize{}, fmt.Errorf("invalid bound size '%s': fractions may only be used for the preferred size", sz)
+		}
+		switch i {
+		case 0:
+			result.Min, result.MinPercent = v.cells(), v.percent()
+		case 1:
+			result.Preferred, result.PreferredPercent = v.cells(), v.percent()
+			if v.unit == unitFraction && v.value > 0 {
+				result.Grow = true
+				result.GrowWeight = v.value * DefaultWeight
+			}
+		case 2:
+			result.Max, result.MaxPercent = v.cells(), v.percent()
+		}
 	}
+	return result, nil
+}
+
+const (
+	unitCells    = ""
+	unitPercent  = "%"
+	unitFraction = "fr"
+)
 
-	if len(nums) == 2 {
-		return BoundSize{Min: nums[0], Preferred: nums[1]}, nil
+// sizeValue is a single number in a bound size, along with its unit.
+type sizeValue struct {
+	value int
+	unit  string
+}
+
+func (v sizeValue) cells() int {
+	if v.unit == unitCells {
+		return v.value
 	}
+	return 0
+}
 
-	// The regex doesn't allow more than 3 numbers.
-	return BoundSize{Min: nums[0], Preferred: nums[1], Max: nums[2]}, nil
+func (v sizeValue) percent() int {
+	if v.unit == unitPercent {
+		return v.value
+	}
+	return 0
+}
+
+// getSizeValues returns the values from the slice until an empty string is reached.
+func getSizeValues(str []string) ([]sizeValue, error) {
+	var result []sizeValue
+	for _, str := range str {
+		if str == "" {
+			break
+		}
+		v := sizeValue{unit: unitCells}
+		for _, unit := range []string{unitPercent, unitFraction} {
+			if strings.HasSuffix(str, unit) {
+				v.unit = unit
+				str = strings.TrimSuffix(str, unit)
+			}
+		}
+		num, err := strconv.Atoi(str)
+		if err != nil {
+			return nil, err
+		}
+		if v.unit == unitPercent && num > 100 {
+			return nil, fmt.Errorf("percentages must not be more than 100%%")
+		}
+		v.value = num
+		result = append(result, v)
+	}
+	return result, nil
 }
 
 type ErrStringLayout struct {
@@ -102,7 +306,7 @@ func makeErrStringLayout(input, msg string, err error) ErrStringLayout {
 }
 
 func convertToLayout(input string) (layout, error) {
-	if input == "" {
+	if strings.TrimSpace(input) == "" {
 		return layout{}, nil
 	}
 
@@ -110,6 +314,9 @@ func convertToLayout(input string) (layout, error) {
 	declarations := strings.Split(input, ",")
 	for _, declaration := range declarations {
 		parts := strings.Fields(declaration)
+		if len(parts) == 0 {
+			return layout{}, makeErrStringLayout(input, "empty constraint", nil)
+		}
 		last := len(parts) == 1
 		part := parts[0]
 		switch part {
@@ -138,13 +345,173 @@ func convertToLayout(input string) (layout, error) {
 				return layout{}, makeErrStringLayout(input, fmt.Sprintf("wrong number of inputs, expected 1 received '%v'", nums), nil)
 			}
 			result.SpanHeight = nums[0]
-		case "grow":
-			result.GrowWidth = true
-			result.GrowHeight = true
-		case "groww", "growx":
-			result.GrowWidth = true
-		case "growh", "growy":
-			result.GrowHeight = true
+		case "split":
+			nums := getNumbers(parts[1:])
+			if len(nums) != 1 || nums[0] < 1 {
+				return layout{}, makeErrStringLayout(input, fmt.Sprintf("wrong number of inputs, expected 1 positive number received '%v'", nums), nil)
+			}
+			result.Split = nums[0]
+		case "grow", "groww", "growx", "growh", "growy":
+			nums := getNumbers(parts[1:])
+			if len(nums) > 1 || (len(nums) == 1 && nums[0] < 0) {
+				return layout{}, makeErrStringLayout(input, fmt.Sprintf("wrong number of inputs, expected 0 or 1 positive number received '%v'", nums), nil)
+			}
+			// "grow 0" is the same as not growing.
+			grow := len(nums) == 0 || nums[0] > 0
+			var weight int
+			if len(nums) == 1 {
+				weight = nums[0]
+			}
+			if part != "growh" && part != "growy" {
+				result.GrowWidth, result.GrowWidthWeight = grow, weight
+			}
+			if part != "groww" && part != "growx" {
+				result.GrowHeight, result.GrowHeightWeight = grow, weight
+			}
+		case "growprio":
+			nums := getNumbers(parts[1:])
+			if len(nums) == 1 {
+				nums = append(nums, nums[0])
+			}
+			if len(nums) != 2 || nums[0] <= 0 || nums[1] <= 0 {
+				return layout{}, makeErrStringLayout(input, fmt.Sprintf("wrong number of inputs, expected 1 or 2 positive numbers received '%v'", nums), nil)
+			}
+			result.GrowWidthPriority, result.GrowHeightPriority = nums[0], nums[1]
+		case "growpriox", "growpriow", "growprioy", "growprioh":
+			nums := getNumbers(parts[1:])
+			if len(nums) != 1 || nums[0] <= 0 {
+				return layout{}, makeErrStringLayout(input, fmt.Sprintf("wrong number of inputs, expected 1 positive number received '%v'", nums), nil)
+			}
+			if part == "growpriox" || part == "growpriow" {
+				result.GrowWidthPriority = nums[0]
+			} else {
+				result.GrowHeightPriority = nums[0]
+			}
+		case "shrink", "shrinkx", "shrinkw", "shrinky", "shrinkh":
+			weight, err := makeShrinkWeight(getNumbers(parts[1:]))
+			if err != nil {
+				return layout{}, makeErrStringLayout(input, "unable to parse shrink weight", err)
+			}
+			if part != "shrinky" && part != "shrinkh" {
+				result.ShrinkWidthWeight = weight
+			}
+			if part != "shrinkx" && part != "shrinkw" {
+				result.ShrinkHeightWeight = weight
+			}
+		case "shrinkprio":
+			nums := getNumbers(parts[1:])
+			if len(nums) == 1 {
+				nums = append(nums, nums[0])
+			}
+			if len(nums) != 2 || nums[0] <= 0 || nums[1] <= 0 {
+				return layout{}, makeErrStringLayout(input, fmt.Sprintf("wrong number of inputs, expected 1 or 2 positive numbers received '%v'", nums), nil)
+			}
+			result.ShrinkWidthPriority, result.ShrinkHeightPriority = nums[0], nums[1]
+		case "shrinkpriox", "shrinkpriow", "shrinkprioy", "shrinkprioh":
+			nums := getNumbers(parts[1:])
+			if len(nums) != 1 || nums[0] <= 0 {
+				return layout{}, makeErrStringLayout(input, fmt.Sprintf("wrong number of inputs, expected 1 positive number received '%v'", nums), nil)
+			}
+			if part == "shrinkpriox" || part == "shrinkpriow" {
+				result.ShrinkWidthPriority = nums[0]
+			} else {
+				result.ShrinkHeightPriority = nums[0]
+			}
+		case "pad", "padding":
+			insets, err := makeInsets(getNumbers(parts[1:]))
+			if err != nil {
+				return layout{}, makeErrStringLayout(input, "unable to parse padding", err)
+			}
+			result.Padding = insets
+		case "margin", "gap":
+			insets, err := makeInsets(getNumbers(parts[1:]))
+			if err != nil {
+				return layout{}, makeErrStringLayout(input, "unable to parse margin", err)
+			}
+			result.Margin = insets
+		case "gapx", "gapy":
+			nums := getNumbers(parts[1:])
+			if len(nums) == 1 {
+				nums = append(nums, nums[0])
+			}
+			if len(nums) != 2 || nums[0] < 0 || nums[1] < 0 {
+				return layout{}, makeErrStringLayout(input, fmt.Sprintf("wrong number of inputs, expected 1 or 2 received '%v'", nums), nil)
+			}
+			if part == "gapx" {
+				result.Margin.Left, result.Margin.Right = nums[0], nums[1]
+			} else {
+				result.Margin.Top, result.Margin.Bottom = nums[0], nums[1]
+			}
+		case "border":
+			result.Cell.Border = BorderNormal
+			if !last {
+				if !isBorderStyle(parts[1]) {
+					return layout{}, makeErrStringLayout(input, fmt.Sprintf("invalid border style '%s'", parts[1]), nil)
+				}
+				result.Cell.Border = BorderStyle(parts[1])
+			}
+		case "id":
+			if len(parts) != 2 {
+				return layout{}, makeErrStringLayout(input, "view name is missing", nil)
+			}
+			result.name = parts[1]
+		case "sizegroup", "sg", "sizegroupx", "sgx", "sizegroupy", "sgy":
+			if len(parts) != 2 {
+				return layout{}, makeErrStringLayout(input, "size group name is missing", nil)
+			}
+			switch part {
+			case "sizegroupx", "sgx":
+				result.SizeGroupWidth = parts[1]
+			case "sizegroupy", "sgy":
+				result.SizeGroupHeight = parts[1]
+			default:
+				result.SizeGroup = parts[1]
+			}
+		case "align", "alignx", "aligny":
+			values := parts[1:]
+			if len(values) == 0 || len(values) > 2 || (part != "align" && len(values) != 1) {
+				return layout{}, makeErrStringLayout(input, fmt.Sprintf("wrong number of inputs, expected 1 or 2 received '%v'", values), nil)
+			}
+			if part == "aligny" {
+				values = append([]string{""}, values...)
+			}
+			if values[0] != "" {
+				if !isAlignX(values[0]) {
+					return layout{}, makeErrStringLayout(input, fmt.Sprintf("invalid horizontal alignment '%s'", values[0]), nil)
+				}
+				result.AlignX = Alignment(values[0])
+			}
+			if len(values) > 1 {
+				if !isAlignY(values[1]) {
+					return layout{}, makeErrStringLayout(input, fmt.Sprintf("invalid vertical alignment '%s'", values[1]), nil)
+				}
+				result.AlignY = Alignment(values[1])
+			}
+		case "corners":
+			if len(parts) != 2 || parts[1] == "" || !isCornerPolicy(parts[1]) {
+				return layout{}, makeErrStringLayout(input, fmt.Sprintf("invalid corner policy '%s', expected own or yield", strings.Join(parts[1:], " ")), nil)
+			}
+			result.Corners = CornerPolicy(parts[1])
+		case "layer", "z":
+			nums := getNumbers(parts[1:])
+			if len(nums) != 1 {
+				return layout{}, makeErrStringLayout(input, fmt.Sprintf("wrong number of inputs, expected 1 received '%v'", nums), nil)
+			}
+			result.Cell.Layer = nums[0]
+		case "hidden":
+			result.hidden = true
+		case "hidemode":
+			if last {
+				return layout{}, makeErrStringLayout(input, "hide mode is missing", nil)
+			}
+			switch parts[1] {
+			case "reserve", "0":
+				result.hideMode = HideModeReserve
+			case "collapse", "1":
+				result.hideMode = HideModeCollapse
+			default:
+				return layout{}, makeErrStringLayout(input, fmt.Sprintf("invalid hide mode '%s'", parts[1]), nil)
+			}
 		case "dock", string(NORTH), string(SOUTH), string(EAST), string(WEST):
 			offset := 0
 			// dock is optional
@@ -163,11 +530,26 @@ func convertToLayout(input string) (layout, error) {
 			if offset < len(parts) {
 				bound, err := parseSize(parts[offset])
 				if err == nil {
+					if bound.Grow {
+						return layout{}, makeErrStringLayout(input, "fractions are not supported for docks", nil)
+					}
 					result.Min = bound.Min
 					result.Preferred = bound.Preferred
 					result.Max = bound.Max
+					result.MinPercent = bound.MinPercent
+					result.PreferredPercent = bound.PreferredPercent
+					result.MaxPercent = bound.MaxPercent
 				}
 			}
+		case "pos":
+			if len(parts) != 3 && len(parts) != 5 {
+				return layout{}, makeErrStringLayout(input, fmt.Sprintf("wrong number of inputs to pos, expected 2 or 4 received %d", len(parts)-1), nil)
+			}
+			pos, err := parsePos(parts[1:])
+			if err != nil {
+				return layout{}, makeErrStringLayout(input, "unable to parse pos", err)
+			}
+			result.Pos, result.positioned = pos, true
 		case "width", "w":
 			if last {
 				return layout{}, makeErrStringLayout(input, "width bound size is missing", nil)
@@ -179,6 +561,12 @@ func convertToLayout(input string) (layout, error) {
 			result.MinWidth = bound.Min
 			result.PreferredWidth = bound.Preferred
 			result.MaxWidth = bound.Max
+			result.MinWidthPercent = bound.MinPercent
+			result.PreferredWidthPercent = bound.PreferredPercent
+			result.MaxWidthPercent = bound.MaxPercent
+			if bound.Grow {
+				result.GrowWidth, result.GrowWidthWeight = true, bound.GrowWeight
+			}
 		case "height", "h":
 			if last {
 				return layout{}, makeErrStringLayout(input, "height bound size is missing", nil)
@@ -190,11 +578,171 @@ func convertToLayout(input string) (layout, error) {
 			result.MinHeight = bound.Min
 			result.PreferredHeight = bound.Preferred
 			result.MaxHeight = bound.Max
+			result.MinHeightPercent = bound.MinPercent
+			result.PreferredHeightPercent = bound.PreferredPercent
+			result.MaxHeightPercent = bound.MaxPercent
+			if bound.Grow {
+				result.GrowHeight, result.GrowHeightWeight = true, bound.GrowWeight
+			}
 		default:
 			return layout{}, makeErrStringLayout(input, fmt.Sprintf("unknown constraint"), nil)
 		}
 	}
 
+	if result.positioned && result.Cardinal != "" {
+		return layout{}, makeErrStringLayout(input, "a view cannot be docked and positioned", nil)
+	}
+
 	// TODO: is it an error to have a Cell and a Dock?
 	return result, nil
 }
+
+// convertToLayoutOptions parses the layout constraints, which apply to the whole layout.
+func convertToLayoutOptions(input string) (layoutOptions, error) {
+	var result layoutOptions
+	if strings.TrimSpace(input) == "" {
+		return result, nil
+	}
+
+	for _, declaration := range strings.Split(input, ",") {
+		parts := strings.Fields(declaration)
+		if len(parts) == 0 {
+			return layoutOptions{}, makeErrStringLayout(input, "empty constraint", nil)
+		}
+		switch parts[0] {
+		case "flowx":
+			result.flowY = false
+		case "flowy":
+			result.flowY = true
+		case "ltr", "lefttoright":
+			result.rightToLeft = false
+		case "rtl", "righttoleft":
+			result.rightToLeft = true
+		case "insets":
+			insets, err := makeInsets(getNumbers(parts[1:]))
+			if err != nil {
+				return layoutOptions{}, makeErrStringLayout(input, "unable to parse insets", err)
+			}
+			result.insets = insets
+		case "gap":
+			nums := getNumbers(parts[1:])
+			if len(nums) == 1 {
+				nums = append(nums, nums[0])
+			}
+			if len(nums) != 2 || nums[0] < 0 || nums[1] < 0 {
+				return layoutOptions{}, makeErrStringLayout(input, fmt.Sprintf("wrong number of inputs, expected 1 or 2 received '%v'", nums), nil)
+			}
+			result.gapX, result.gapY = nums[0], nums[1]
+		case "gapx", "gapy":
+			nums := getNumbers(parts[1:])
+			if len(nums) != 1 || nums[0] < 0 {
+				return layoutOptions{}, makeErrStringLayout(input, fmt.Sprintf("wrong number of inputs, expected 1 received '%v'", nums), nil)
+			}
+			if part := parts[0]; part == "gapx" {
+				result.gapX = nums[0]
+			} else {
+				result.gapY = nums[0]
+			}
+		default:
+			return layoutOptions{}, makeErrStringLayout(input, "unknown layout constraint", nil)
+		}
+	}
+	return result, nil
+}
+
+// convertToAxisConstraints parses the row or column constraints of a layout, E.g. "[20!]2[grow][30:n:50]".
+// Each entry is in square brackets and a number between the brackets is the gap between two entries.
+// The gaps have one more entry than the preferences, gaps[i] is the gap before entry i and the last
+// one is the gap after the final entry. Gaps which are not set are defaultGap.
+func convertToAxisConstraints(input string) (PreferenceGroup, []int, error) {
+	var prefs PreferenceGroup
+	gaps := []int{defaultGap}
+	rest := input
+	for strings.TrimSpace(rest) != "" {
+		open := strings.IndexByte(rest, '[')
+		if open < 0 {
+			open = len(rest)
+		}
+		if gap := strings.TrimSpace(rest[:open]); gap != "" {
+			num, err := strconv.Atoi(gap)
+			if err != nil || num < 0 {
+				return nil, nil, makeErrStringLayout(input, fmt.Sprintf("invalid gap '%s'", gap), nil)
+			}
+			gaps[len(gaps)-1] = num
+		}
+		if open == len(rest) {
+			break
+		}
+
+		end := strings.IndexByte(rest[open:], ']')
+		if end < 0 {
+			return nil, nil, makeErrStringLayout(input, "missing closing bracket", nil)
+		}
+		end += open
+		pref, err := convertToAxisConstraint(rest[open+1 : end])
+		if err != nil {
+			return nil, nil, makeErrStringLayout(input, "unable to parse constraint", err)
+		}
+		prefs = append(prefs, pref)
+		gaps = append(gaps, defaultGap)
+		rest = rest[end+1:]
+	}
+	return prefs, gaps, nil
+}
+
+// convertToAxisConstraint parses the contents of a single row or column constraint, E.g. "30:n:50, grow".
+func convertToAxisConstraint(input string) (BoundSize, error) {
+	var result BoundSize
+	if strings.TrimSpace(input) == "" {
+		return result, nil
+	}
+
+	for _, declaration := range strings.Split(input, ",") {
+		parts := strings.Fields(declaration)
+		if len(parts) == 0 {
+			return BoundSize{}, fmt.Errorf("empty constraint")
+		}
+		switch part := parts[0]; part {
+		case "grow":
+			nums := getNumbers(parts[1:])
+			if len(nums) > 1 || (len(nums) == 1 && nums[0] < 0) {
+				return BoundSize{}, fmt.Errorf("wrong number of inputs, expected 0 or 1 positive number received '%v'", nums)
+			}
+			result.Grow = len(nums) == 0 || nums[0] > 0
+			result.GrowWeight = 0
+			if len(nums) == 1 {
+				result.GrowWeight = nums[0]
+			}
+		case "growprio", "shrinkprio":
+			nums := getNumbers(parts[1:])
+			if len(nums) != 1 || nums[0] <= 0 {
+				return BoundSize{}, fmt.Errorf("wrong number of inputs, expected 1 positive number received '%v'", nums)
+			}
+			if part == "growprio" {
+				result.GrowPriority = nums[0]
+			} else {
+				result.ShrinkPriority = nums[0]
+			}
+		case "shrink":
+			weight, err := makeShrinkWeight(getNumbers(parts[1:]))
+			if err != nil {
+				return BoundSize{}, err
+			}
+			result.ShrinkWeight = weight
+		default:
+			if len(parts) != 1 {
+				return BoundSize{}, fmt.Errorf("unknown constraint '%s'", declaration)
+			}
+			bound, err := parseSize(part)
+			if err != nil {
+				return BoundSize{}, err
+			}
+			result.Min, result.Preferred, result.Max = bound.Min, bound.Preferred, bound.Max
+			result.MinPercent, result.PreferredPercent, result.MaxPercent = bound.MinPercent, bound.PreferredPercent, bound.MaxPercent
+			if bound.Grow {
+				result.Grow, result.GrowWeight = true, bound.GrowWeight
+			}
+		}
+	}
+	return result, nil
+}
@@ -14,6 +14,14 @@ func TestErrorUnwrap(t *testing.T) {
 	assert.ErrorIs(t, err, inner)
 }
 
+func TestIsBorderStyle(t *testing.T) {
+	for _, b := range []BorderStyle{BorderNormal, BorderRounded, BorderThick, BorderDouble} {
+		assert.True(t, isBorderStyle(string(b)))
+	}
+	assert.False(t, isBorderStyle(""))
+	assert.False(t, isBorderStyle("not a border"))
+}
+
 func TestIsCardinal(t *testing.T) {
 	for _, c := range []Cardinal{NORTH, SOUTH, EAST, WEST} {
 		assert.True(t, isCardinal(string(c)))
@@ -53,6 +61,18 @@ func TestBorderSizeRegexp(t *testing.T) {
 		}, {
 			in:  "1:2:3:4",
 			out: nil,
+		}, {
+			in:  "30%",
+			out: []string{"30%", "30%", "", "", ""},
+		}, {
+			in:  "1fr",
+			out: []string{"1fr", "1fr", "", "", ""},
+		}, {
+			in:  "50%:0:80",
+			out: []string{"50%:0:80", "50%", "0", "80", ""},
+		}, {
+			in:  "1px",
+			out: nil,
 		},
 	}
 
@@ -117,6 +137,33 @@ func TestPartSize(t *testing.T) {
 		}, {
 			in:  ":10:",
 			out: BoundSize{Preferred: 10},
+		}, {
+			in:  "30%",
+			out: BoundSize{PreferredPercent: 30},
+		}, {
+			in:  "50%:n:80",
+			out: BoundSize{MinPercent: 50, Max: 80},
+		}, {
+			in:  "10:50%:90%",
+			out: BoundSize{Min: 10, PreferredPercent: 50, MaxPercent: 90},
+		}, {
+			in:  "25%!",
+			out: BoundSize{MinPercent: 25, PreferredPercent: 25, MaxPercent: 25},
+		}, {
+			in:  "101%",
+			err: "invalid bound size '101%': percentages must not be more than 100%",
+		}, {
+			in:  "1fr",
+			out: BoundSize{Grow: true, GrowWeight: 100},
+		}, {
+			in:  "10:2fr",
+			out: BoundSize{Min: 10, Grow: true, GrowWeight: 200},
+		}, {
+			in:  "1fr!",
+			err: "invalid bound size '1fr!': use '!' with cells or percentages",
+		}, {
+			in:  "1fr:10",
+			err: "invalid bound size '1fr:10': fractions may only be used for the preferred size",
 		},
 	}
 
@@ -164,6 +211,34 @@ func TestConvertString(t *testing.T) {
 			name:  "growy,growh",
 			inArr: []string{"growy", "growh"},
 			out:   layout{Cell: Cell{GrowHeight: true}},
+		}, {
+			name:  "grow weight",
+			inArr: []string{"grow 200", "growx 200, growy 200"},
+			out:   layout{Cell: Cell{GrowWidth: true, GrowHeight: true, GrowWidthWeight: 200, GrowHeightWeight: 200}},
+		}, {
+			name: "growx weight",
+			in:   "growx 50",
+			out:  layout{Cell: Cell{GrowWidth: true, GrowWidthWeight: 50}},
+		}, {
+			name:  "grow 0",
+			inArr: []string{"grow 0", "grow, grow 0"},
+			out:   layout{},
+		}, {
+			name:  "invalid grow",
+			inArr: []string{"grow -1", "growx 1 2"},
+			err:   "wrong number of inputs, expected 0 or 1",
+		}, {
+			name:  "growprio",
+			inArr: []string{"growprio 5", "growprio 5 5", "growpriox 5, growprioy 5"},
+			out:   layout{Cell: Cell{GrowWidthPriority: 5, GrowHeightPriority: 5}},
+		}, {
+			name: "growprio x y",
+			in:   "growprio 1 2",
+			out:  layout{Cell: Cell{GrowWidthPriority: 1, GrowHeightPriority: 2}},
+		}, {
+			name:  "invalid growprio",
+			inArr: []string{"growprio", "growprio 0", "growpriox -1", "growprioy"},
+			err:   "wrong number of inputs",
 		}, {
 			name:  "invalid dock-wrong direction",
 			inArr: []string{"dock left", "dock 1"},
@@ -232,6 +307,202 @@ func TestConvertString(t *testing.T) {
 			name: "command after multi-token command",
 			in:   "width 1:2:3, grow, span 1 2, wrap",
 			out:  layout{wrap: true, Cell: Cell{SpanWidth: 1, SpanHeight: 2, GrowWidth: true, GrowHeight: true, MinWidth: 1, PreferredWidth: 2, MaxWidth: 3}},
+		}, {
+			name: "split",
+			in:   "split 3",
+			out:  layout{Cell: Cell{Split: 3}},
+		}, {
+			name:  "invalid split",
+			inArr: []string{"split", "split 0", "split 1 2"},
+			err:   "wrong number of inputs, expected 1 positive number",
+		}, {
+			name:  "pad",
+			inArr: []string{"pad 1", "pad 1 1", "padding 1 1 1 1"},
+			out:   layout{Cell: Cell{Padding: Insets{Top: 1, Left: 1, Bottom: 1, Right: 1}}},
+		}, {
+			name: "pad vertical horizontal",
+			in:   "pad 1 2",
+			out:  layout{Cell: Cell{Padding: Insets{Top: 1, Left: 2, Bottom: 1, Right: 2}}},
+		}, {
+			name:  "margin",
+			inArr: []string{"margin 1 2 3 4", "gap 1 2 3 4", "gapy 1 3, gapx 2 4"},
+			out:   layout{Cell: Cell{Margin: Insets{Top: 1, Left: 2, Bottom: 3, Right: 4}}},
+		}, {
+			name: "gapx",
+			in:   "gapx 2",
+			out:  layout{Cell: Cell{Margin: Insets{Left: 2, Right: 2}}},
+		}, {
+			name:  "invalid insets",
+			inArr: []string{"pad", "pad 1 2 3", "margin 1 2 3 4 5"},
+			err:   "wrong number of insets",
+		}, {
+			name:  "negative insets",
+			inArr: []string{"pad -1", "margin 1 -2", "gap -1"},
+			err:   "insets must not be negative",
+		}, {
+			name:  "invalid gapx and gapy",
+			inArr: []string{"gapx", "gapy 1 2 3", "gapx -1"},
+			err:   "wrong number of inputs, expected 1 or 2",
+		}, {
+			name:  "border",
+			inArr: []string{"border", "border normal"},
+			out:   layout{Cell: Cell{Border: BorderNormal}},
+		}, {
+			name: "border rounded",
+			in:   "border rounded",
+			out:  layout{Cell: Cell{Border: BorderRounded}},
+		}, {
+			name: "invalid border",
+			in:   "border dotted",
+			err:  "invalid border style 'dotted'",
+		}, {
+			name: "hidden",
+			in:   "hidden",
+			out:  layout{hidden: true},
+		}, {
+			name:  "hidemode reserve",
+			inArr: []string{"hidemode reserve", "hidemode 0"},
+			out:   layout{hideMode: HideModeReserve},
+		}, {
+			name:  "hidemode collapse",
+			inArr: []string{"hidemode collapse", "hidemode 1"},
+			out:   layout{hideMode: HideModeCollapse},
+		}, {
+			name: "hidemode missing",
+			in:   "hidemode",
+			err:  "hide mode is missing",
+		}, {
+			name: "hidemode invalid",
+			in:   "hidemode 3",
+			err:  "invalid hide mode '3'",
+		}, {
+			name:  "shrink",
+			inArr: []string{"shrink", "shrink 100", "shrinkx, shrinky", "shrinkw 100, shrinkh 100"},
+			out:   layout{Cell: Cell{ShrinkWidthWeight: DefaultWeight, ShrinkHeightWeight: DefaultWeight}},
+		}, {
+			name: "shrinkx weight",
+			in:   "shrinkx 300",
+			out:  layout{Cell: Cell{ShrinkWidthWeight: 300}},
+		}, {
+			name:  "shrink 0",
+			inArr: []string{"shrink 0", "shrinkx 0, shrinky 0"},
+			out:   layout{Cell: Cell{ShrinkWidthWeight: NoShrink, ShrinkHeightWeight: NoShrink}},
+		}, {
+			name:  "invalid shrink",
+			inArr: []string{"shrink -1", "shrinkx 1 2"},
+			err:   "unable to parse shrink weight",
+		}, {
+			name:  "shrinkprio",
+			inArr: []string{"shrinkprio 5", "shrinkprio 5 5", "shrinkpriox 5, shrinkprioy 5"},
+			out:   layout{Cell: Cell{ShrinkWidthPriority: 5, ShrinkHeightPriority: 5}},
+		}, {
+			name: "shrinkprio x y",
+			in:   "shrinkprio 1 2",
+			out:  layout{Cell: Cell{ShrinkWidthPriority: 1, ShrinkHeightPriority: 2}},
+		}, {
+			name:  "invalid shrinkprio",
+			inArr: []string{"shrinkprio", "shrinkprio 0", "shrinkpriox -1", "shrinkprioy"},
+			err:   "wrong number of inputs",
+		}, {
+			name: "pos",
+			in:   "pos 5 10",
+			out:  layout{positioned: true, Pos: Pos{X: 5, Y: 10}},
+		}, {
+			name:  "pos center",
+			inArr: []string{"pos center center 40 50%", "pos 50% 50% 40 50%"},
+			out:   layout{positioned: true, Pos: Pos{XPercent: 50, YPercent: 50, Width: 40, HeightPercent: 50}},
+		}, {
+			name: "pos from far edge",
+			in:   "pos -1 -0 30 3",
+			out:  layout{positioned: true, Pos: Pos{X: -1, XPercent: 100, YPercent: 100, Width: 30, Height: 3}},
+		}, {
+			name:  "pos wrong number of inputs",
+			inArr: []string{"pos", "pos 1", "pos 1 2 3"},
+			err:   "wrong number of inputs to pos",
+		}, {
+			name:  "invalid pos",
+			inArr: []string{"pos x 1", "pos 1 -x", "pos 1 1 1fr 1", "pos 1 1 1 -1", "pos 101% 1"},
+			err:   "unable to parse pos",
+		}, {
+			name:  "layer",
+			inArr: []string{"layer 2", "z 2"},
+			out:   layout{Cell: Cell{Layer: 2}},
+		}, {
+			name: "negative layer",
+			in:   "layer -1",
+			out:  layout{Cell: Cell{Layer: -1}},
+		}, {
+			name:  "invalid layer",
+			inArr: []string{"layer", "layer 1 2"},
+			err:   "wrong number of inputs",
+		}, {
+			name:  "align",
+			inArr: []string{"align center", "alignx center"},
+			out:   layout{Cell: Cell{AlignX: AlignCenter}},
+		}, {
+			name:  "align x y",
+			inArr: []string{"align right bottom", "alignx right, aligny bottom"},
+			out:   layout{Cell: Cell{AlignX: AlignRight, AlignY: AlignBottom}},
+		}, {
+			name: "aligny",
+			in:   "aligny center",
+			out:  layout{Cell: Cell{AlignY: AlignCenter}},
+		}, {
+			name:  "align wrong number of inputs",
+			inArr: []string{"align", "align left top center", "alignx left top", "aligny"},
+			err:   "wrong number of inputs",
+		}, {
+			name:  "invalid horizontal alignment",
+			inArr: []string{"align top", "alignx bottom"},
+			err:   "invalid horizontal alignment",
+		}, {
+			name:  "invalid vertical alignment",
+			inArr: []string{"align left right", "aligny left"},
+			err:   "invalid vertical alignment",
+		}, {
+			name:  "empty declaration",
+			inArr: []string{"wrap,", "width 4,,grow", ", grow", " , "},
+			err:   "empty constraint",
+		}, {
+			name: "whitespace",
+			in:   "  ",
+			out:  layout{},
+		}, {
+			name: "id",
+			in:   "id sidebar, width 20",
+			out:  layout{name: "sidebar", Cell: Cell{PreferredWidth: 20}},
+		}, {
+			name:  "id missing name",
+			inArr: []string{"id", "id side bar"},
+			err:   "view name is missing",
+		}, {
+			name:  "sizegroup",
+			inArr: []string{"sizegroup buttons", "sg buttons"},
+			out:   layout{Cell: Cell{SizeGroup: "buttons"}},
+		}, {
+			name:  "sizegroupx",
+			inArr: []string{"sizegroupx a, sizegroupy b", "sgx a, sgy b"},
+			out:   layout{Cell: Cell{SizeGroupWidth: "a", SizeGroupHeight: "b"}},
+		}, {
+			name:  "sizegroup missing name",
+			inArr: []string{"sizegroup", "sgx", "sgy a b"},
+			err:   "size group name is missing",
+		}, {
+			name: "corners",
+			in:   "corners own",
+			out:  layout{Dock: Dock{Corners: CornersOwn}},
+		}, {
+			name: "corners yield",
+			in:   "corners yield",
+			out:  layout{Dock: Dock{Corners: CornersYield}},
+		}, {
+			name:  "invalid corners",
+			inArr: []string{"corners", "corners left", "corners own yield"},
+			err:   "invalid corner policy",
+		}, {
+			name: "pos and dock",
+			in:   "pos 1 1, north",
+			err:  "a view cannot be docked and positioned",
 		}, {
 			name:  "unknown constraint",
 			inArr: []string{"unknown constraint", "100"},
@@ -260,3 +531,87 @@ func TestConvertString(t *testing.T) {
 		}
 	}
 }
+
+func TestConvertToLayoutOptions(t *testing.T) {
+	testcases := []struct {
+		in  string
+		out layoutOptions
+		err string
+	}{
+		{in: "", out: layoutOptions{}},
+		{in: "flowx, ltr", out: layoutOptions{}},
+		{in: "flowy", out: layoutOptions{flowY: true}},
+		{in: "rtl", out: layoutOptions{rightToLeft: true}},
+		{in: "flowy, righttoleft", out: layoutOptions{flowY: true, rightToLeft: true}},
+		{in: "insets 1 2", out: layoutOptions{insets: Insets{Top: 1, Left: 2, Bottom: 1, Right: 2}}},
+		{in: "gap 1", out: layoutOptions{gapX: 1, gapY: 1}},
+		{in: "gap 1 2", out: layoutOptions{gapX: 1, gapY: 2}},
+		{in: "gapx 3, gapy 4", out: layoutOptions{gapX: 3, gapY: 4}},
+		{in: "flowy,", err: "empty constraint"},
+		{in: "wrap", err: "unknown layout constraint"},
+		{in: "insets 1 2 3", err: "unable to parse insets"},
+		{in: "gapx", err: "wrong number of inputs"},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+			out, err := convertToLayoutOptions(tc.in)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.out, out)
+			}
+		})
+	}
+}
+
+func TestConvertToAxisConstraints(t *testing.T) {
+	testcases := []struct {
+		in    string
+		prefs PreferenceGroup
+		gaps  []int
+		err   string
+	}{
+		{in: "", gaps: []int{defaultGap}},
+		{in: "[]", prefs: PreferenceGroup{{}}, gaps: []int{defaultGap, defaultGap}},
+		{
+			in:    "[20!][grow][30:n:50]",
+			prefs: PreferenceGroup{{Min: 20, Preferred: 20, Max: 20}, {Grow: true}, {Min: 30, Max: 50}},
+			gaps:  []int{defaultGap, defaultGap, defaultGap, defaultGap},
+		},
+		{
+			in:    "1 [10] 2 [20] 3",
+			prefs: PreferenceGroup{{Preferred: 10}, {Preferred: 20}},
+			gaps:  []int{1, 2, 3},
+		},
+		{
+			in:    "[50%, grow 200, growprio 300][10:20, shrink 0, shrinkprio 5][1fr]",
+			prefs: PreferenceGroup{{PreferredPercent: 50, Grow: true, GrowWeight: 200, GrowPriority: 300}, {Min: 10, Preferred: 20, ShrinkWeight: NoShrink, ShrinkPriority: 5}, {Grow: true, GrowWeight: DefaultWeight}},
+			gaps:  []int{defaultGap, defaultGap, defaultGap, defaultGap},
+		},
+		{in: "[10", err: "missing closing bracket"},
+		{in: "[10]x[20]", err: "invalid gap 'x'"},
+		{in: "[10] -1 [20]", err: "invalid gap '-1'"},
+		{in: "[wrap]", err: "unable to parse constraint"},
+		{in: "[10, ]", err: "empty constraint"},
+		{in: "[growprio]", err: "wrong number of inputs"},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+			prefs, gaps, err := convertToAxisConstraints(tc.in)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.prefs, prefs)
+				assert.Equal(t, tc.gaps, gaps)
+			}
+		})
+	}
+}
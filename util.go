@@ -19,3 +19,11 @@ func max(left int, args ...int) int {
 	}
 	return ret
 }
+
+func sum(args []int) int {
+	ret := 0
+	for _, v := range args {
+		ret += v
+	}
+	return ret
+}